@@ -1,33 +1,107 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/adelapazborrero/music_download/internal/app"
+	"github.com/adelapazborrero/music_download/internal/archive"
+	"github.com/adelapazborrero/music_download/internal/cli"
+	"github.com/adelapazborrero/music_download/internal/config"
+	"github.com/adelapazborrero/music_download/internal/player"
 	"github.com/adelapazborrero/music_download/internal/youtube"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	// Load settings: defaults, then config file, then environment
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	// Command line flags override everything else
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Subcommands run without the TUI
+	if flag.NArg() > 0 && cli.IsCommand(flag.Arg(0)) {
+		os.Exit(cli.Run(flag.Args(), cfg, os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(cli.ExitUsage)
+	}
+
 	// Check dependencies first
 	if err := youtube.CheckDependencies(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// Parse command line arguments
-	var query string
-	if len(os.Args) >= 2 {
-		// Old behavior: command line arguments
-		query = strings.Join(os.Args[1:], " ")
+	// The archive marks owned songs and lets playlists skip them
+	arch, err := cfg.Archive()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if n := arch.Damaged(); n > 0 {
+		fmt.Printf("Warning: skipped %d damaged lines in %s\n", n, arch.Path())
+	}
+
+	// Unfinished playlist jobs are saved here and offered on the menu
+	jobs, err := cfg.Jobs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+
+	// Remaining arguments are treated as a search query
 	// If no arguments, query will be empty and menu will be shown
+	query := strings.Join(flag.Args(), " ")
+
+	// The supervisor owns the preview player so it can be stopped on exit
+	previews := player.NewSupervisor(cfg.PlayerCommand)
 
 	// Create and run the bubbletea program
-	p := tea.NewProgram(app.InitialModel(query))
+	p := tea.NewProgram(app.InitialModel(query, app.Options{
+		Backend:         archive.Wrap(cfg.Backend(), arch),
+		PlaylistWorkers: cfg.PlaylistWorkers,
+		Download:        cfg.DownloadOptions(),
+		SearchLimit:     cfg.SearchLimit,
+		PlayerCommand:   cfg.PlayerCommand,
+		Previews:        previews,
+		Archive:         arch,
+		SkipArchived:    cfg.SkipArchived,
+		AlbumMode:       cfg.AlbumMode,
+		Jobs:            jobs,
+		SkimLength:      cfg.SkimLength,
+		SkimOffset:      cfg.SkimOffset,
+	}))
+
+	// Closing the terminal or a kill request quits like pressing q, so the
+	// cleanup below still runs
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		p.Quit()
+	}()
+
 	m, err := p.Run()
+	// Don't leave previews or downloads running in the background after
+	// quitting
+	if finalModel, ok := m.(app.Model); ok {
+		finalModel.Shutdown()
+	}
+	previews.Stop()
+	youtube.KillAll()
 
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -36,10 +110,6 @@ func main() {
 
 	// Print final message if any
 	if finalModel, ok := m.(app.Model); ok {
-		if finalModel.Error() != nil {
-			fmt.Printf("Error: %v\n", finalModel.Error())
-			os.Exit(1)
-		}
 		if finalModel.Message() != "" {
 			fmt.Println(finalModel.Message())
 		}
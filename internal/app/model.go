@@ -1,8 +1,12 @@
 package app
 
 import (
-	"os/exec"
+	"context"
 
+	"github.com/adelapazborrero/music_download/internal/archive"
+	"github.com/adelapazborrero/music_download/internal/config"
+	"github.com/adelapazborrero/music_download/internal/player"
+	"github.com/adelapazborrero/music_download/internal/resume"
 	"github.com/adelapazborrero/music_download/internal/youtube"
 )
 
@@ -20,6 +24,9 @@ const (
 	ScreenDetails
 	ScreenDownloading
 	ScreenPlaylistDownloading
+	ScreenQueue
+	ScreenPlaylistSummary
+	ScreenTags
 )
 
 // Model holds the application state
@@ -27,47 +34,271 @@ type Model struct {
 	screen              Screen
 	searchQuery         string
 	results             []youtube.SearchResult
+	marked              map[string]bool
 	cursor              int
 	menuCursor          int
 	textInput           string
 	selected            *youtube.VideoMetadata
+	tags                *youtube.Tags
+	tagForm             youtube.Tags
+	tagCursor           int
 	action              string
-	err                 error
-	downloading         bool
 	message             string
 	height              int
-	previewing          bool
-	previewCmd          *exec.Cmd
+	previews            *player.Supervisor
+	preview             *player.Player
+	previewStatus       player.Status
+	radio               bool
+	radioIndex          int
+	skim                bool
+	skimSnippet         player.Snippet
+	skimCtx             context.Context
+	skimCancel          context.CancelFunc
+	durations           map[string]int
 	fromURL             bool
 	searchLimit         int
+	searchPageSize      int
 	playlistItems       []youtube.SearchResult
 	playlistProgress    int
 	playlistTotal       int
 	playlistSuccess     int
 	playlistFailed      int
+	playlistSkipped     int
 	playlistFailedItems []string
+	playlistActive      []activeItem
+	playlistCursor      int
+	playlistWorkers     int
+	playlistName        string
+	playlistOptions     youtube.DownloadOptions
+	playlistAlbum       *youtube.Album
+	albumMode           bool
+	playlistState       *resume.Job
+	playlistDownload    *youtube.PlaylistDownload
+	playlistReturn      Screen
+	jobs                *resume.Store
+	resumable           []*resume.Job
+	summaryCursor       int
+	summaryNotice       string
+	menuNotice          string
+	fetchCtx            context.Context
+	fetchCancel         context.CancelFunc
+	fetchReturn         Screen
+	downloadOptions     youtube.DownloadOptions
+	format              youtube.AudioFormat
+	quality             youtube.Quality
+	lyrics              bool
+	backend             youtube.Backend
+	archive             *archive.Archive
+	skipArchived        bool
+	queue               []queueJob
+	queueNextID         int
+	queueCursor         int
+	queueNotice         string
+	queueFocus          int
+	queueReturn         Screen
 }
 
-// Getters for private fields (needed by main.go)
-func (m Model) Error() error {
-	return m.err
+// activeItem is a playlist item currently being downloaded by a worker
+type activeItem struct {
+	index    int
+	title    string
+	progress youtube.Progress
+}
+
+// Options configures the initial application state
+type Options struct {
+	// Backend is the media extractor; nil means yt-dlp
+	Backend youtube.Backend
+	// PlaylistWorkers is the number of playlist items downloaded in parallel
+	PlaylistWorkers int
+	// Download sets the output directory, filename template and the
+	// default audio format and quality
+	Download youtube.DownloadOptions
+	// SearchLimit is the number of results fetched per page
+	SearchLimit int
+	// PlayerCommand is the preview player and its arguments; the video URL
+	// is appended
+	PlayerCommand []string
+	// Previews plays the previews and must be stopped before exiting; nil
+	// creates one for PlayerCommand
+	Previews *player.Supervisor
+	// Archive lists previously downloaded videos; nil disables the owned
+	// badges and skipping
+	Archive *archive.Archive
+	// SkipArchived skips playlist items that are already in Archive
+	SkipArchived bool
+	// AlbumMode downloads playlists as albums unless toggled off on the
+	// playlist input screen
+	AlbumMode bool
+	// SkimLength and SkimOffset set the seconds played of each result in
+	// skim mode and where they start, in percent of the song (0 being the
+	// beginning)
+	SkimLength int
+	SkimOffset int
+	// Jobs saves playlist progress so unfinished downloads can be resumed
+	// from the menu; nil disables resuming
+	Jobs *resume.Store
 }
 
+// Getters for private fields (needed by main.go)
 func (m Model) Message() string {
 	return m.message
 }
 
 // InitialModel creates the initial application state
-func InitialModel(query string) Model {
+func InitialModel(query string, opts Options) Model {
+	if opts.Backend == nil {
+		opts.Backend = youtube.NewYTDLPBackend()
+	}
+	if opts.PlaylistWorkers < 1 {
+		opts.PlaylistWorkers = youtube.DefaultPlaylistWorkers
+	}
+	defaults := config.Default()
+	if opts.SearchLimit < 1 {
+		opts.SearchLimit = defaults.SearchLimit
+	}
+	if len(opts.PlayerCommand) == 0 {
+		opts.PlayerCommand = defaults.PlayerCommand
+	}
+	if opts.SkimLength < 1 {
+		opts.SkimLength = defaults.SkimLength
+	}
+	if opts.Previews == nil {
+		opts.Previews = player.NewSupervisor(opts.PlayerCommand)
+	}
+	if opts.Download.Format == "" {
+		opts.Download.Format = youtube.DefaultFormat
+	}
+	if opts.Download.Quality == "" {
+		opts.Download.Quality = youtube.DefaultQuality
+	}
+	m := Model{
+		screen:          ScreenMenu,
+		searchLimit:     opts.SearchLimit,
+		searchPageSize:  opts.SearchLimit,
+		previews:        opts.Previews,
+		playlistWorkers: opts.PlaylistWorkers,
+		downloadOptions: opts.Download,
+		format:          opts.Download.Format,
+		quality:         opts.Download.Quality,
+		lyrics:          opts.Download.Lyrics.Enabled,
+		backend:         opts.Backend,
+		archive:         opts.Archive,
+		skipArchived:    opts.SkipArchived,
+		albumMode:       opts.AlbumMode,
+		skimSnippet:     player.Snippet{Offset: float64(opts.SkimOffset), Length: float64(opts.SkimLength)},
+		jobs:            opts.Jobs,
+	}
+	// Jobs left unfinished by an earlier run are offered on the menu
+	resumable, err := opts.Jobs.List()
+	if err != nil {
+		m.menuNotice = "⚠ Unfinished playlists can't be resumed: " + err.Error()
+	}
+	m.resumable = resumable
 	if query != "" {
-		return Model{
-			screen:      ScreenSearch,
-			searchQuery: query,
-			searchLimit: 20,
-		}
+		m.screen = ScreenSearch
+		m.searchQuery = query
+		m.textInput = query
+		m.beginFetch(ScreenSearchInput)
+	}
+	return m
+}
+
+// beginFetch starts a cancellable search or metadata/playlist lookup,
+// cancelling any earlier one. Cancelling returns to the screen ret.
+func (m *Model) beginFetch(ret Screen) context.Context {
+	m.cancelFetch()
+	m.fetchCtx, m.fetchCancel = context.WithCancel(context.Background())
+	m.fetchReturn = ret
+	return m.fetchCtx
+}
+
+// cancelFetch stops the running fetch, if any
+func (m *Model) cancelFetch() {
+	if m.fetchCancel != nil {
+		m.fetchCancel()
+		m.fetchCancel = nil
+	}
+}
+
+// Shutdown cancels every background download and lookup still running.
+// Call it once the program has exited, whichever screen it quit from.
+func (m Model) Shutdown() {
+	m.cancelAllJobs()
+	if m.playlistDownload != nil {
+		m.playlistDownload.Cancel()
 	}
-	return Model{
-		screen:      ScreenMenu,
-		searchLimit: 20,
+	m.cancelFetch()
+	m.endSkim()
+}
+
+// selectedOptions returns the download options with the format, quality
+// and lyrics setting currently picked in the UI
+func (m Model) selectedOptions() youtube.DownloadOptions {
+	opts := m.downloadOptions
+	opts.Format = m.format
+	opts.Quality = m.quality
+	opts.Lyrics.Enabled = m.lyrics
+	return opts
+}
+
+// resetFormat restores the configured default format, quality and lyrics
+// setting
+func (m *Model) resetFormat() {
+	m.format = m.downloadOptions.Format
+	m.quality = m.downloadOptions.Quality
+	m.lyrics = m.downloadOptions.Lyrics.Enabled
+}
+
+// downloadRequest builds the request for downloading the selected video
+func (m Model) downloadRequest() youtube.DownloadRequest {
+	req := youtube.DownloadRequest{
+		VideoID:         m.selected.ID,
+		Title:           m.selected.Title,
+		Channel:         m.selected.Channel,
+		DownloadOptions: m.selectedOptions(),
+	}
+	if m.tags != nil {
+		req.Tags = *m.tags
+	}
+	return req
+}
+
+// playlistJob builds the worker pool job for the current playlist. With
+// skipArchived set, items already in the archive are skipped.
+func (m Model) playlistJob(skipArchived bool) youtube.PlaylistJob {
+	job := youtube.PlaylistJob{
+		Name:    m.playlistName,
+		Items:   m.playlistItems,
+		Options: m.playlistOptions,
+		Workers: m.playlistWorkers,
+		Album:   m.playlistAlbum,
+	}
+	if skipArchived && m.archive != nil {
+		job.Skip = m.archive.Has
+	}
+	return job
+}
+
+// playlistDestination describes where playlist items are saved
+func (m Model) playlistDestination() string {
+	req := youtube.DownloadRequest{
+		Playlist:        m.playlistName,
+		DownloadOptions: m.playlistOptions,
+	}
+	if m.playlistAlbum != nil {
+		req = m.playlistAlbum.Track(req, 0, 0)
+	}
+	return req.Destination()
+}
+
+// markedResults returns the selected search results in list order
+func (m Model) markedResults() []youtube.SearchResult {
+	var items []youtube.SearchResult
+	for _, r := range m.results {
+		if m.marked[r.ID] {
+			items = append(items, r)
+		}
 	}
+	return items
 }
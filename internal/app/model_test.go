@@ -0,0 +1,445 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/adelapazborrero/music_download/internal/resume"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel returns a model downloading through fake into a temporary
+// directory. The preview player can't start, so no playback is polled.
+func newTestModel(t *testing.T, query string, fake *youtube.FakeBackend) Model {
+	t.Helper()
+	m := InitialModel(query, Options{
+		Backend:       fake,
+		PlayerCommand: []string{filepath.Join(t.TempDir(), "no-player")},
+		Download:      youtube.DownloadOptions{OutputDir: t.TempDir()},
+		Jobs:          resume.NewStore(t.TempDir()),
+	})
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+// run executes cmd and every command the resulting updates return, the way
+// the bubbletea runtime would, until nothing is left to do
+func run(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	cmds := []tea.Cmd{cmd}
+	for steps := 0; len(cmds) > 0; steps++ {
+		if steps > 1000 {
+			t.Fatal("commands never settled")
+		}
+		cmd, cmds = cmds[0], cmds[1:]
+		if cmd == nil {
+			continue
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			cmds = append(cmds, msg...)
+		case tea.QuitMsg:
+			t.Fatalf("quit on screen %d: %s", m.screen, m.message)
+		default:
+			next, cmd := m.Update(msg)
+			m = next.(Model)
+			cmds = append(cmds, cmd)
+		}
+	}
+	return m
+}
+
+// send sends a single key to m without running the command it returns
+func send(m Model, key string) (Model, tea.Cmd) {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	case "ctrl+u":
+		msg = tea.KeyMsg{Type: tea.KeyCtrlU}
+	}
+	next, cmd := m.Update(msg)
+	return next.(Model), cmd
+}
+
+// press sends the keys to m in turn, running the commands they return
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, k := range keys {
+		next, cmd := send(m, k)
+		m = run(t, next, cmd)
+	}
+	return m
+}
+
+// typeText types s one character at a time
+func typeText(t *testing.T, m Model, s string) Model {
+	t.Helper()
+	for _, r := range s {
+		m = press(t, m, string(r))
+	}
+	return m
+}
+
+func TestSearchAndQueueFlow(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.SearchResults["daft punk"] = []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Daft Punk - Get Lucky", Channel: "Daft Punk", Duration: 248}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Daft Punk - One More Time", Channel: "Daft Punk", Duration: 320}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "ccccccccccc", Title: "Daft Punk - Around the World", Channel: "Daft Punk"}),
+	}
+	fake.DownloadErrors["bbbbbbbbbbb"] = &youtube.Warning{Err: errors.New("cover art: no thumbnail")}
+	fake.DownloadErrors["ccccccccccc"] = &youtube.DownloadError{Category: youtube.CategoryPrivate, Message: "Private video"}
+
+	m := newTestModel(t, "daft punk", fake)
+	m = run(t, m, m.Init())
+	if m.screen != ScreenResults || len(m.results) != 3 {
+		t.Fatalf("after searching: screen %d with %d results, want the 3 results", m.screen, len(m.results))
+	}
+
+	// Open the first result and queue it
+	m = press(t, m, "enter")
+	if m.screen != ScreenDetails || m.selected == nil || m.selected.Duration != 248 {
+		t.Fatalf("after enter: screen %d, selected %+v; want the details with full metadata", m.screen, m.selected)
+	}
+	m = press(t, m, "d")
+
+	// Then the other two from the results list
+	for _, keys := range [][]string{{"esc", "down", "enter", "d"}, {"esc", "down", "enter", "d"}} {
+		m = press(t, m, keys...)
+	}
+
+	m = press(t, m, "Q")
+	if m.screen != ScreenQueue || len(m.queue) != 3 {
+		t.Fatalf("queue screen %d with %d jobs, want 3 jobs", m.screen, len(m.queue))
+	}
+	tests := []struct {
+		title       string
+		wantStatus  jobStatus
+		wantFile    string
+		wantWarning string
+	}{
+		{"Daft Punk - Get Lucky", jobDone, "Daft Punk - Get Lucky.mp3", ""},
+		{"Daft Punk - One More Time", jobDone, "Daft Punk - One More Time.mp3", "cover art: no thumbnail"},
+		{"Daft Punk - Around the World", jobFailed, ".", ""}, // no path
+	}
+	for i, tt := range tests {
+		job := m.queue[i]
+		if job.req.Title != tt.title || job.status != tt.wantStatus || filepath.Base(job.path) != tt.wantFile || job.warning != tt.wantWarning {
+			t.Errorf("job %d = %q %v %q %q; want %q %v %q %q", i, job.req.Title, job.status, job.path, job.warning, tt.title, tt.wantStatus, tt.wantFile, tt.wantWarning)
+		}
+	}
+	if got := len(fake.Downloads()); got != 3 {
+		t.Errorf("backend got %d downloads, want 3", got)
+	}
+	if !strings.Contains(m.View(), "✗ Failed: Daft Punk - Around the World") {
+		t.Errorf("queue view doesn't report the failure:\n%s", m.View())
+	}
+
+	// The job's own screen explains the warning and esc goes back
+	m = press(t, m, "down", "enter")
+	if m.screen != ScreenDownloading || !strings.Contains(m.View(), "cover art: no thumbnail") {
+		t.Errorf("job screen %d doesn't show the warning:\n%s", m.screen, m.View())
+	}
+	m = press(t, m, "esc")
+	if m.screen != ScreenQueue {
+		t.Errorf("esc on the job screen went to screen %d, want the queue", m.screen)
+	}
+}
+
+func TestPlaylistFlow(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	items := []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Intro"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Hidden"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "ccccccccccc", Title: "Gone"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "ddddddddddd", Title: "Also Hidden"}),
+	}
+	fake.Playlists["PLtest"] = youtube.Playlist{ID: "PLtest", Title: "Mix", Items: items}
+	fake.DownloadErrors["bbbbbbbbbbb"] = &youtube.DownloadError{Category: youtube.CategoryPrivate, Message: "Private video"}
+	fake.DownloadErrors["ddddddddddd"] = &youtube.DownloadError{Category: youtube.CategoryPrivate, Message: "Private video"}
+	fake.DownloadErrors["ccccccccccc"] = &youtube.DownloadError{Category: youtube.CategoryRemoved, Message: "Video unavailable"}
+
+	m := newTestModel(t, "", fake)
+	m = press(t, m, "down", "down", "enter")
+	if m.screen != ScreenPlaylistInput {
+		t.Fatalf("menu opened screen %d, want the playlist input", m.screen)
+	}
+	m = typeText(t, m, "https://www.youtube.com/playlist?list=PLtest")
+	m = press(t, m, "enter")
+
+	if m.screen != ScreenPlaylistSummary {
+		t.Fatalf("screen %d after the download, want the summary", m.screen)
+	}
+	if m.playlistSuccess != 1 || m.playlistFailed != 3 {
+		t.Errorf("%d succeeded and %d failed, want 1 and 3", m.playlistSuccess, m.playlistFailed)
+	}
+	if jobs, _ := m.jobs.List(); len(jobs) != 0 {
+		t.Errorf("%d jobs left saved after finishing, want none", len(jobs))
+	}
+
+	view := m.View()
+	for _, want := range []string{
+		"Failed downloads:",
+		"Private videos (2): Hidden, Also Hidden",
+		"Removed or unavailable (1): Gone",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("summary doesn't show %q:\n%s", want, view)
+		}
+	}
+
+	m = press(t, m, "enter")
+	if m.screen != ScreenMenu || m.playlistState != nil {
+		t.Errorf("enter on the summary went to screen %d, want the menu", m.screen)
+	}
+}
+
+func TestPlaylistRetryCancel(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	items := []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Intro"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Flaky"}),
+	}
+	fake.Playlists["PLtest"] = youtube.Playlist{ID: "PLtest", Title: "Mix", Items: items}
+	fake.DownloadErrors["bbbbbbbbbbb"] = &youtube.DownloadError{Category: youtube.CategoryNetwork, Message: "Connection reset by peer"}
+
+	m := newTestModel(t, "", fake)
+	m = press(t, m, "down", "down", "enter")
+	m = typeText(t, m, "https://www.youtube.com/playlist?list=PLtest")
+	m = press(t, m, "enter")
+	if m.screen != ScreenPlaylistSummary {
+		t.Fatalf("screen %d after the download, want the summary", m.screen)
+	}
+
+	// Retry the failed song, then cancel before it finishes
+	m, _ = send(m, "r")
+	if m.screen != ScreenPlaylistDownloading {
+		t.Fatalf("r on the summary went to screen %d, want the download", m.screen)
+	}
+	m, _ = send(m, "esc")
+	if m.screen != ScreenMenu {
+		t.Errorf("cancelling the retry went to screen %d, want the menu", m.screen)
+	}
+	if len(m.resumable) != 1 || m.resumable[0].Title() != "Mix" {
+		t.Errorf("resumable jobs = %v, want the cancelled playlist", m.resumable)
+	}
+	if view := m.View(); !strings.Contains(view, "Mix") || !strings.Contains(view, "cancelled") {
+		t.Errorf("menu doesn't offer the cancelled job:\n%s", view)
+	}
+}
+
+func TestLookupErrorsKeepRunning(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.SearchResults["found"] = []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Song"}),
+	}
+
+	// A queued download must survive the failed lookups below
+	m := newTestModel(t, "found", fake)
+	m = run(t, m, m.Init())
+	m = press(t, m, "enter", "d", "esc", "esc")
+	if m.screen != ScreenMenu || len(m.queue) != 1 {
+		t.Fatalf("screen %d with %d jobs, want the menu and 1 job", m.screen, len(m.queue))
+	}
+
+	tests := []struct {
+		name       string
+		keys       []string
+		input      string
+		wantScreen Screen
+		wantError  string
+	}{
+		{"search without results", []string{"enter"}, "typo", ScreenSearchInput, "no results found"},
+		{"missing video", []string{"down", "enter"}, "https://youtu.be/zzzzzzzzzzz", ScreenURLInput, "does not exist"},
+		{"missing playlist", []string{"down", "down", "enter"}, "https://www.youtube.com/playlist?list=PLgone", ScreenPlaylistInput, "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := press(t, m, tt.keys...)
+			m = typeText(t, m, tt.input)
+			m = press(t, m, "enter")
+			if m.screen != tt.wantScreen || !strings.Contains(m.View(), tt.wantError) {
+				t.Errorf("screen %d, want %d showing %q:\n%s", m.screen, tt.wantScreen, tt.wantError, m.View())
+			}
+			if len(m.queue) != 1 || m.queue[0].status != jobDone {
+				t.Errorf("queued download was lost: %+v", m.queue)
+			}
+		})
+	}
+}
+
+func TestBulkDownload(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.SearchResults["lofi"] = []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "One"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Two"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "ccccccccccc", Title: "Three"}),
+	}
+
+	m := newTestModel(t, "lofi", fake)
+	m = run(t, m, m.Init())
+	m = press(t, m, " ", "down", "down", " ")
+	if view := m.View(); !strings.Contains(view, "[x] One") || !strings.Contains(view, "[ ] Two") || !strings.Contains(view, "D download 2 marked") {
+		t.Fatalf("results view doesn't show the selection:\n%s", view)
+	}
+
+	m = press(t, m, "D")
+	if m.playlistSuccess != 2 || len(m.marked) != 0 {
+		t.Errorf("%d succeeded with %d still marked, want 2 and none", m.playlistSuccess, len(m.marked))
+	}
+	var got []string
+	for _, req := range fake.Downloads() {
+		got = append(got, req.VideoID)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "aaaaaaaaaaa,ccccccccccc" {
+		t.Errorf("backend downloaded %v, want the marked songs", got)
+	}
+
+	// a marks everything, and again clears it
+	m = press(t, m, "enter", "enter")
+	m = typeText(t, m, "lofi")
+	m = press(t, m, "enter")
+	if m = press(t, m, "a"); len(m.markedResults()) != 3 {
+		t.Errorf("a marked %d results, want 3", len(m.markedResults()))
+	}
+	if m = press(t, m, "a"); len(m.markedResults()) != 0 {
+		t.Errorf("a again left %d results marked", len(m.markedResults()))
+	}
+}
+
+func TestResumeJob(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	items := []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Intro"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Outro"}),
+	}
+	store := resume.NewStore(t.TempDir())
+	job := resume.NewJob("Mix", items, youtube.DownloadOptions{OutputDir: t.TempDir()}, false)
+	job.Record(youtube.PlaylistDownloadProgressMsg{Index: 0, Success: true, Path: "Intro.mp3"})
+	if err := store.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	m := InitialModel("", Options{Backend: fake, Jobs: store})
+	if view := m.View(); !strings.Contains(view, `Resume "Mix" (1/2 done)`) {
+		t.Fatalf("menu doesn't offer the unfinished job:\n%s", view)
+	}
+	m = press(t, m, "down", "down", "down", "down", "enter")
+	if m.screen != ScreenPlaylistSummary || m.playlistSuccess != 2 {
+		t.Errorf("screen %d with %d succeeded, want the summary and 2", m.screen, m.playlistSuccess)
+	}
+	if got := fake.Downloads(); len(got) != 1 || got[0].VideoID != "bbbbbbbbbbb" {
+		t.Errorf("downloads = %+v, want only the unfinished item", got)
+	}
+	if jobs, _ := store.List(); len(jobs) != 0 || len(m.resumable) != 0 {
+		t.Errorf("%d jobs saved and %d offered after resuming, want none", len(jobs), len(m.resumable))
+	}
+}
+
+func TestUnreadableJobs(t *testing.T) {
+	// A file where the jobs directory should be
+	jobs := filepath.Join(t.TempDir(), "jobs")
+	if err := os.WriteFile(jobs, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := InitialModel("", Options{Backend: youtube.NewFakeBackend(), Jobs: resume.NewStore(jobs)})
+	if view := m.View(); !strings.Contains(view, "can't be resumed") {
+		t.Errorf("menu doesn't report the unreadable jobs:\n%s", view)
+	}
+}
+
+func TestRadio(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.SearchResults["lofi"] = []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "One"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "bbbbbbbbbbb", Title: "Two"}),
+		fake.AddVideo(youtube.VideoMetadata{ID: "ccccccccccc", Title: "Three"}),
+	}
+	radioModel := func(player ...string) Model {
+		m := InitialModel("lofi", Options{
+			Backend:       fake,
+			PlayerCommand: player,
+			Download:      youtube.DownloadOptions{OutputDir: t.TempDir()},
+		})
+		t.Cleanup(m.Shutdown)
+		t.Cleanup(m.previews.Stop)
+		return run(t, m, m.Init())
+	}
+
+	t.Run("plays to the end", func(t *testing.T) {
+		// Every preview ends at once
+		m := radioModel("true")
+		m = press(t, m, "r")
+		if m.radio || m.message != "Radio finished" {
+			t.Errorf("radio = %v with message %q, want it finished", m.radio, m.message)
+		}
+	})
+
+	t.Run("controls", func(t *testing.T) {
+		// The previews keep playing; their wait commands are never run
+		m := radioModel("sh", "-c", "sleep 30")
+		m, _ = send(m, "r")
+		m, _ = send(m, "n")
+		if !m.radio || m.radioIndex != 1 || !strings.Contains(m.message, "Radio: Two (2/3)") {
+			t.Fatalf("radio = %v at %d with message %q, want the second result", m.radio, m.radioIndex, m.message)
+		}
+
+		m = press(t, m, "d")
+		if len(m.queue) != 1 || m.queue[0].req.VideoID != "bbbbbbbbbbb" || !m.radio {
+			t.Errorf("d queued %d jobs with radio %v, want the playing song and radio on", len(m.queue), m.radio)
+		}
+
+		m, _ = send(m, "esc")
+		if m.radio || m.preview != nil {
+			t.Error("leaving the results kept the radio playing")
+		}
+	})
+}
+
+func TestTagEditor(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.SearchResults["lucky"] = []youtube.SearchResult{
+		fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Daft Punk - Get Lucky (Official Video)", Channel: "Daft Punk"}),
+	}
+
+	m := newTestModel(t, "lucky", fake)
+	m = run(t, m, m.Init())
+	m = press(t, m, "enter", "t")
+	if m.screen != ScreenTags || m.tagForm.Artist != "Daft Punk" || m.tagForm.Title != "Get Lucky" {
+		t.Fatalf("tag editor on screen %d starts with %+v, want the parsed artist and title", m.screen, m.tagForm)
+	}
+
+	// A bad year is refused
+	m = press(t, m, "down", "down", "down", "down")
+	m = typeText(t, m, "13")
+	m = press(t, m, "enter")
+	if m.screen != ScreenTags || !strings.Contains(m.View(), "year") {
+		t.Fatalf("saving a bad year went to screen %d:\n%s", m.screen, m.View())
+	}
+
+	m = press(t, m, "ctrl+u")
+	m = typeText(t, m, "2013")
+	m = press(t, m, "down", "down", "down", "down", "ctrl+u")
+	m = typeText(t, m, "Daft Punk feat. Pharrell")
+	m = press(t, m, "enter")
+	if m.screen != ScreenDetails || m.tags == nil {
+		t.Fatalf("saving went to screen %d with tags %+v, want the details", m.screen, m.tags)
+	}
+
+	m = press(t, m, "d")
+	got := fake.Downloads()
+	if len(got) != 1 || got[0].Tags.Artist != "Daft Punk feat. Pharrell" || got[0].Tags.Year != "2013" || got[0].Tags.Title != "Get Lucky" {
+		t.Errorf("downloads = %+v, want the edited tags", got)
+	}
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adelapazborrero/music_download/internal/utils"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jobStatus is the state of a background download job
+type jobStatus int
+
+const (
+	jobQueued jobStatus = iota
+	jobRunning
+	jobDone
+	jobFailed
+	jobCancelled
+)
+
+func (s jobStatus) String() string {
+	switch s {
+	case jobQueued:
+		return "queued"
+	case jobRunning:
+		return "running"
+	case jobDone:
+		return "done"
+	case jobFailed:
+		return "failed"
+	case jobCancelled:
+		return "cancelled"
+	}
+	return "unknown"
+}
+
+// queueJob is a single download in the background queue
+type queueJob struct {
+	id       int
+	req      youtube.DownloadRequest
+	status   jobStatus
+	progress youtube.Progress
+	path     string
+	warning  string
+	err      error
+	download *youtube.Download
+}
+
+// enqueue adds req to the download queue and starts it if nothing is running
+func (m *Model) enqueue(req youtube.DownloadRequest) tea.Cmd {
+	m.queueNextID++
+	m.queue = append(m.queue, queueJob{id: m.queueNextID, req: req})
+	return m.startNextJob()
+}
+
+// startNextJob starts the first queued job unless one is already running
+func (m *Model) startNextJob() tea.Cmd {
+	for _, job := range m.queue {
+		if job.status == jobRunning {
+			return nil
+		}
+	}
+	for i := range m.queue {
+		if m.queue[i].status == jobQueued {
+			job := &m.queue[i]
+			job.status = jobRunning
+			job.progress = youtube.Progress{}
+			job.err = nil
+			job.download = youtube.StartDownload(m.backend, job.req)
+			return job.download.Next()
+		}
+	}
+	return nil
+}
+
+// jobFor returns the job running download d
+func (m *Model) jobFor(d *youtube.Download) *queueJob {
+	for i := range m.queue {
+		if m.queue[i].download == d {
+			return &m.queue[i]
+		}
+	}
+	return nil
+}
+
+// jobByID returns the job with the given id
+func (m *Model) jobByID(id int) *queueJob {
+	for i := range m.queue {
+		if m.queue[i].id == id {
+			return &m.queue[i]
+		}
+	}
+	return nil
+}
+
+// finishJob records the outcome of a job's download
+func (m *Model) finishJob(job *queueJob, msg youtube.DownloadCompleteMsg) {
+	job.download = nil
+	switch {
+	case errors.Is(msg.Err, youtube.ErrCancelled):
+		job.status = jobCancelled
+		m.queueNotice = "Cancelled: " + job.req.Title
+	case msg.Err != nil:
+		job.status = jobFailed
+		job.err = msg.Err
+		m.queueNotice = "✗ Failed: " + job.req.Title
+	case msg.Warning != "":
+		job.status = jobDone
+		job.path = msg.Path
+		job.warning = msg.Warning
+		m.queueNotice = "⚠ Saved " + msg.Path + " (" + msg.Warning + ")"
+	default:
+		job.status = jobDone
+		job.path = msg.Path
+		m.queueNotice = "✓ Saved " + msg.Path
+	}
+}
+
+// moveJob swaps the queued job at the cursor with its neighbour in
+// direction delta (-1 up, +1 down). Only queued jobs can be reordered.
+func (m *Model) moveJob(delta int) {
+	i, j := m.queueCursor, m.queueCursor+delta
+	if j < 0 || j >= len(m.queue) {
+		return
+	}
+	if m.queue[i].status != jobQueued || m.queue[j].status != jobQueued {
+		return
+	}
+	m.queue[i], m.queue[j] = m.queue[j], m.queue[i]
+	m.queueCursor = j
+}
+
+// cancelJob cancels the job at the cursor, whether queued or running
+func (m *Model) cancelJob() {
+	if m.queueCursor >= len(m.queue) {
+		return
+	}
+	job := &m.queue[m.queueCursor]
+	switch job.status {
+	case jobQueued:
+		job.status = jobCancelled
+	case jobRunning:
+		// The job is marked cancelled when its DownloadCompleteMsg arrives
+		job.download.Cancel()
+	}
+}
+
+// retryJob puts a failed or cancelled job at the cursor back in the queue
+func (m *Model) retryJob() tea.Cmd {
+	if m.queueCursor >= len(m.queue) {
+		return nil
+	}
+	job := &m.queue[m.queueCursor]
+	if job.status != jobFailed && job.status != jobCancelled {
+		return nil
+	}
+	job.status = jobQueued
+	job.err = nil
+	job.warning = ""
+	return m.startNextJob()
+}
+
+// clearFinishedJobs removes done jobs from the queue
+func (m *Model) clearFinishedJobs() {
+	kept := m.queue[:0]
+	for _, job := range m.queue {
+		if job.status != jobDone {
+			kept = append(kept, job)
+		}
+	}
+	m.queue = kept
+	if m.queueCursor >= len(m.queue) {
+		m.queueCursor = max(len(m.queue)-1, 0)
+	}
+}
+
+// cancelAllJobs stops the running job and drops everything queued
+func (m *Model) cancelAllJobs() {
+	for i := range m.queue {
+		switch m.queue[i].status {
+		case jobQueued:
+			m.queue[i].status = jobCancelled
+		case jobRunning:
+			m.queue[i].download.Cancel()
+		}
+	}
+}
+
+// queueStatusLine summarizes the queue for the status line, or returns ""
+// when the queue is empty
+func queueStatusLine(m Model) string {
+	if len(m.queue) == 0 {
+		return ""
+	}
+
+	counts := map[jobStatus]int{}
+	var running *queueJob
+	for i, job := range m.queue {
+		counts[job.status]++
+		if job.status == jobRunning {
+			running = &m.queue[i]
+		}
+	}
+
+	finished := counts[jobDone] + counts[jobFailed] + counts[jobCancelled]
+	s := fmt.Sprintf("Queue: %d/%d finished", finished, len(m.queue))
+	if counts[jobFailed] > 0 {
+		s += fmt.Sprintf(" • %d failed", counts[jobFailed])
+	}
+	if running != nil {
+		s += fmt.Sprintf(" • ⬇ %s %.0f%%", utils.Truncate(running.req.Title, 30), running.progress.Percent)
+	} else if m.queueNotice != "" {
+		s += " • " + m.queueNotice
+	}
+	return s
+}
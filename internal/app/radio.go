@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/adelapazborrero/music_download/internal/utils"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Radio mode previews the search results one after another, starting the
+// next result when a preview ends. The results list stays usable while it
+// plays. Skim mode is radio mode playing only a snippet of each result.
+
+// playRadio plays the result at index in radio mode. Past the end of the
+// list radio mode stops.
+func (m *Model) playRadio(index int) tea.Cmd {
+	if index < 0 || index >= len(m.results) {
+		m.stopRadio()
+		m.message = "Radio finished"
+		return nil
+	}
+	m.radio = true
+	m.radioIndex = index
+	result := m.results[index]
+	if !m.skim {
+		cmd := m.startPreview(result.ID)
+		if m.preview == nil {
+			// The player couldn't start; startPreview explained why
+			m.radio = false
+			return nil
+		}
+		m.message = fmt.Sprintf("♪ Radio: %s (%d/%d)", result.Title, index+1, len(m.results))
+		return cmd
+	}
+
+	// Snippets start at an offset into the song, so the duration is
+	// fetched first
+	duration, ok := m.durations[result.ID]
+	if !ok {
+		m.stopPreview()
+		m.message = fmt.Sprintf("Skim: loading %s (%d/%d)...", result.Title, index+1, len(m.results))
+		return fetchDuration(m.skimCtx, m.backend, result.ID)
+	}
+	snippet := m.skimSnippet
+	snippet.Duration = float64(duration)
+	cmd := m.previewStarted(m.previews.PlaySnippet(youtube.VideoURL(result.ID), snippet))
+	if m.preview == nil {
+		m.stopRadio()
+		return nil
+	}
+	m.message = fmt.Sprintf("♪ Skim: %s (%d/%d) • space to stop here", result.Title, index+1, len(m.results))
+
+	// Fetch the next duration while this snippet plays
+	if next := index + 1; next < len(m.results) {
+		if _, ok := m.durations[m.results[next].ID]; !ok {
+			cmd = tea.Batch(cmd, fetchDuration(m.skimCtx, m.backend, m.results[next].ID))
+		}
+	}
+	return cmd
+}
+
+// startSkim plays snippets of the results from index on
+func (m *Model) startSkim(index int) tea.Cmd {
+	m.stopRadio()
+	m.skim = true
+	m.skimCtx, m.skimCancel = context.WithCancel(context.Background())
+	return m.playRadio(index)
+}
+
+// markSkimmed marks the result being skimmed and stops skimming there.
+// The snippet plays to its end.
+func (m *Model) markSkimmed() {
+	result := m.results[m.radioIndex]
+	m.marked = markID(m.marked, result.ID)
+	m.cursor = m.radioIndex
+	m.radio = false
+	m.endSkim()
+	m.message = fmt.Sprintf("Marked %s, skim stopped", result.Title)
+}
+
+// stopRadio leaves radio and skim mode and stops the preview
+func (m *Model) stopRadio() {
+	if m.radio {
+		m.stopPreview()
+	}
+	m.radio = false
+	m.endSkim()
+}
+
+// endSkim cancels the duration lookups of skim mode
+func (m *Model) endSkim() {
+	if m.skimCancel != nil {
+		m.skimCancel()
+		m.skimCancel = nil
+	}
+	m.skim = false
+}
+
+// durationFetchedMsg carries the duration of a result for skim mode. It is
+// 0 when the metadata couldn't be fetched.
+type durationFetchedMsg struct {
+	id       string
+	duration int
+}
+
+// fetchDuration looks up the duration of a video
+func fetchDuration(ctx context.Context, b youtube.Backend, videoID string) tea.Cmd {
+	return func() tea.Msg {
+		metadata, err := b.Metadata(ctx, videoID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return durationFetchedMsg{id: videoID}
+		}
+		return durationFetchedMsg{id: videoID, duration: metadata.Duration}
+	}
+}
+
+// setDuration caches a video's duration, creating the map on first use
+func setDuration(durations map[string]int, id string, duration int) map[string]int {
+	if durations == nil {
+		durations = map[string]int{}
+	}
+	durations[id] = duration
+	return durations
+}
+
+// queueRadioTrack adds the playing result to the download queue with the
+// default format; playback continues
+func (m *Model) queueRadioTrack() tea.Cmd {
+	if !m.radio {
+		return nil
+	}
+	result := m.results[m.radioIndex]
+	cmd := m.enqueue(youtube.DownloadRequest{
+		VideoID:         result.ID,
+		Title:           result.Title,
+		Channel:         result.Channel,
+		DownloadOptions: m.downloadOptions,
+	})
+	m.message = fmt.Sprintf("Added %s to download queue (%d jobs) • Q to view queue",
+		utils.Truncate(result.Title, 40), len(m.queue))
+	return cmd
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/adelapazborrero/music_download/internal/ui"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// tagFields are the fields of the tag editor, in display order
+var tagFields = []struct {
+	label string
+	value func(*youtube.Tags) *string
+}{
+	{"Artist", func(t *youtube.Tags) *string { return &t.Artist }},
+	{"Title", func(t *youtube.Tags) *string { return &t.Title }},
+	{"Album", func(t *youtube.Tags) *string { return &t.Album }},
+	{"Album artist", func(t *youtube.Tags) *string { return &t.AlbumArtist }},
+	{"Year", func(t *youtube.Tags) *string { return &t.Year }},
+	{"Genre", func(t *youtube.Tags) *string { return &t.Genre }},
+	{"Track", func(t *youtube.Tags) *string { return &t.Track }},
+	{"Comment", func(t *youtube.Tags) *string { return &t.Comment }},
+}
+
+// defaultTags returns the tags the selected video is downloaded with when
+// they aren't edited: the parsed artist and title, plus what the metadata
+// says about the album and year
+func (m Model) defaultTags() youtube.Tags {
+	req := youtube.DownloadRequest{Title: m.selected.Title, Channel: m.selected.Channel}
+	tags := req.WrittenTags()
+	tags.Album = m.selected.Album
+	tags.Year = m.selected.Year()
+	tags.Comment = youtube.VideoURL(m.selected.ID)
+	return tags
+}
+
+// openTags shows the tag editor for the selected video, starting from the
+// previously saved edits if there are any
+func (m Model) openTags() (tea.Model, tea.Cmd) {
+	if m.tags != nil {
+		m.tagForm = *m.tags
+	} else {
+		m.tagForm = m.defaultTags()
+	}
+	m.tagCursor = 0
+	m.message = ""
+	m.screen = ScreenTags
+	return m, nil
+}
+
+func (m Model) updateTags(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	field := tagFields[m.tagCursor].value(&m.tagForm)
+	m.message = ""
+
+	// Handle paste events
+	if msg.Paste {
+		*field += string(msg.Runes)
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.stopPreview()
+		return m, tea.Quit
+	case "esc":
+		m.screen = ScreenDetails
+		return m, nil
+	case "enter":
+		if err := m.tagForm.Validate(); err != nil {
+			m.message = ui.ErrorStyle.Render(err.Error())
+			return m, nil
+		}
+		tags := m.tagForm
+		m.tags = &tags
+		m.message = "Tags saved • d to download"
+		m.screen = ScreenDetails
+		return m, nil
+	case "up", "shift+tab":
+		m.tagCursor = (m.tagCursor + len(tagFields) - 1) % len(tagFields)
+		return m, nil
+	case "down", "tab":
+		m.tagCursor = (m.tagCursor + 1) % len(tagFields)
+		return m, nil
+	case "ctrl+u":
+		*field = ""
+		return m, nil
+	case "ctrl+r":
+		// Start over from the metadata
+		m.tagForm = m.defaultTags()
+		return m, nil
+	case "backspace":
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+		}
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyRunes:
+		*field += string(msg.Runes)
+	case tea.KeySpace:
+		*field += " "
+	}
+	return m, nil
+}
+
+func tagsView(m Model) string {
+	s := ui.TitleStyle.Render("Edit Tags") + "\n\n"
+	for i, f := range tagFields {
+		value := *f.value(&m.tagForm)
+		line := fmt.Sprintf("%-13s %s", f.label+":", value)
+		if i == m.tagCursor {
+			s += ui.SelectedStyle.Render("> "+line+"_") + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+
+	req := m.downloadRequest()
+	req.Tags = m.tagForm
+	s += fmt.Sprintf("\n  Saved as: %s\n", req.Destination())
+
+	if m.message != "" {
+		s += "\n  " + m.message + "\n"
+	}
+	s += ui.HelpStyle.Render("\nup/down move • type to edit • ctrl+u clear field • ctrl+r reset\nenter save • esc cancel • ctrl+c quit")
+	return s
+}
@@ -1,9 +1,14 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/adelapazborrero/music_download/internal/player"
+	"github.com/adelapazborrero/music_download/internal/resume"
 	"github.com/adelapazborrero/music_download/internal/youtube"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -16,7 +21,7 @@ func (m Model) Init() tea.Cmd {
 	cmds = append(cmds, tea.EnableBracketedPaste)
 
 	if m.searchQuery != "" {
-		cmds = append(cmds, youtube.SearchYouTube(m.searchQuery, m.searchLimit))
+		cmds = append(cmds, youtube.SearchYouTube(m.fetchCtx, m.backend, m.searchQuery, m.searchLimit))
 	}
 
 	if len(cmds) > 0 {
@@ -46,70 +51,190 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateResults(msg)
 		case ScreenDetails:
 			return m.updateDetails(msg)
+		case ScreenQueue:
+			return m.updateQueue(msg)
+		case ScreenDownloading:
+			return m.updateDownloading(msg)
+		case ScreenPlaylistSummary:
+			return m.updatePlaylistSummary(msg)
+		case ScreenSearch, ScreenLoading:
+			return m.updateFetching(msg)
+		case ScreenPlaylistDownloading:
+			return m.updatePlaylistDownloading(msg)
+		case ScreenTags:
+			return m.updateTags(msg)
 		}
 
 	case youtube.SearchCompleteMsg:
+		// Results of a cancelled search are dropped
+		if errors.Is(msg.Err, youtube.ErrCancelled) || m.screen != ScreenSearch {
+			return m, nil
+		}
+		m.cancelFetch()
+		m.message = ""
 		if msg.Err != nil {
-			m.err = msg.Err
-			return m, tea.Quit
+			// Back to where the search started; queued downloads keep running
+			if m.fetchReturn == ScreenResults {
+				m.searchLimit -= m.searchPageSize
+			}
+			m.screen = m.fetchReturn
+			m.message = "Error: " + msg.Err.Error()
+			return m, nil
 		}
 		m.results = msg.Results
 		m.screen = ScreenResults
 		return m, nil
 
 	case youtube.MetadataFetchedMsg:
+		if errors.Is(msg.Err, youtube.ErrCancelled) || !m.awaitsMetadata(msg.Metadata) {
+			return m, nil
+		}
+		m.cancelFetch()
 		if msg.Err != nil {
-			m.err = msg.Err
-			return m, tea.Quit
+			m.message = "Error: " + msg.Err.Error()
+			// Details opened from the results keep the partial metadata
+			if m.screen == ScreenLoading {
+				m.fromURL = false
+				m.screen = m.fetchReturn
+			}
+			return m, nil
 		}
 
 		// Update with full metadata
 		m.selected = msg.Metadata
-		m.screen = ScreenDetails
+		m.durations = setDuration(m.durations, msg.Metadata.ID, msg.Metadata.Duration)
+		// Tag edits in progress aren't interrupted
+		if m.screen != ScreenTags {
+			m.screen = ScreenDetails
+		}
 
-		// Only start preview if not already previewing (e.g., from URL input)
-		// When coming from search results, preview is already started
-		if !m.previewing {
+		// Only start preview for the URL input flow. When coming from search
+		// results, preview is already started (or was stopped by the user)
+		if m.fromURL && m.preview == nil {
 			// Auto-start preview (for URL input flow)
-			url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", msg.Metadata.ID)
-			cmd := exec.Command("mpv", "--no-video", "--ytdl-format=bestaudio", url)
-			m.previewCmd = cmd
-			go cmd.Run()
-			m.previewing = true
-			m.message = "Playing preview... (press 's' to stop)"
+			return m, m.startPreview(msg.Metadata.ID)
 		}
 
 		return m, nil
 
-	case youtube.DownloadCompleteMsg:
-		m.downloading = false
-		if msg.Err != nil {
-			m.message = "Download failed: " + msg.Err.Error()
-		} else {
-			m.message = "✓ Download complete!"
+	case previewStatusMsg:
+		// Stale polls of a stopped preview end here
+		if msg.player != m.preview {
+			return m, nil
+		}
+		if msg.err == nil {
+			m.previewStatus = msg.status
+		}
+		return m, pollPreview(m.preview)
+
+	case durationFetchedMsg:
+		m.durations = setDuration(m.durations, msg.id, msg.duration)
+		// Skim mode waits for the duration before playing a result
+		if m.radio && m.skim && m.preview == nil && m.radioIndex < len(m.results) && m.results[m.radioIndex].ID == msg.id {
+			return m, m.playRadio(m.radioIndex)
 		}
-		// Return to details screen instead of quitting
-		m.screen = ScreenDetails
 		return m, nil
 
+	case player.PreviewEndedMsg:
+		if msg.Player != m.preview {
+			return m, nil
+		}
+		m.preview = nil
+		if m.radio {
+			return m, m.playRadio(m.radioIndex + 1)
+		}
+		if m.screen == ScreenDetails {
+			m.message = "Preview finished"
+		}
+		return m, nil
+
+	case player.PreviewFailedMsg:
+		if msg.Player != m.preview {
+			return m, nil
+		}
+		m.preview = nil
+		if m.radio {
+			// Unplayable results are skipped
+			return m, m.playRadio(m.radioIndex + 1)
+		}
+		if m.screen == ScreenDetails {
+			m.message = "Preview failed: " + msg.Err.Error()
+		}
+		return m, nil
+
+	case youtube.DownloadProgressMsg:
+		if job := m.jobFor(msg.Download); job != nil {
+			job.progress = msg.Progress
+		}
+		return m, msg.Download.Next()
+
+	case youtube.DownloadCompleteMsg:
+		if job := m.jobFor(msg.Download); job != nil {
+			m.finishJob(job, msg)
+		}
+		return m, m.startNextJob()
+
 	case youtube.PlaylistFetchedMsg:
+		if errors.Is(msg.Err, youtube.ErrCancelled) || m.screen != ScreenLoading {
+			return m, nil
+		}
+		m.cancelFetch()
 		if msg.Err != nil {
-			m.err = msg.Err
-			return m, tea.Quit
+			m.screen = m.fetchReturn
+			m.message = "Error: " + msg.Err.Error()
+			return m, nil
 		}
-		m.playlistItems = msg.Items
-		m.playlistTotal = len(msg.Items)
-		m.playlistSuccess = 0
-		m.playlistFailed = 0
-		m.playlistFailedItems = []string{}
 		m.message = fmt.Sprintf("Found %d songs in playlist. Starting download...", len(msg.Items))
-		m.screen = ScreenPlaylistDownloading
-		return m, youtube.DownloadPlaylist(msg.Items)
+		var album *youtube.Album
+		if m.albumMode {
+			a := youtube.NewAlbum(msg.Title, msg.Items)
+			album = &a
+		}
+		return m, m.startPlaylist(msg.Title, msg.Items, album, true)
+
+	case youtube.PlaylistItemStartedMsg:
+		if msg.Download != m.playlistDownload {
+			return m, msg.Download.Next()
+		}
+		m.playlistActive = append(m.playlistActive, activeItem{index: msg.Index, title: msg.Title})
+		return m, msg.Download.Next()
+
+	case youtube.PlaylistItemProgressMsg:
+		if msg.Download != m.playlistDownload {
+			return m, msg.Download.Next()
+		}
+		for i := range m.playlistActive {
+			if m.playlistActive[i].index == msg.Index {
+				m.playlistActive[i].progress = msg.Progress
+			}
+		}
+		return m, msg.Download.Next()
 
 	case youtube.PlaylistDownloadProgressMsg:
+		// Messages of a cancelled download are drained and dropped
+		if msg.Download != m.playlistDownload {
+			return m, msg.Download.Next()
+		}
 		// Update progress and counts
 		m.playlistProgress = msg.Current
-		if msg.Success {
+		m.playlistActive = removeActiveItem(m.playlistActive, msg.Index)
+		var saveErr error
+		if m.playlistState != nil {
+			// Saved after every item so a crash loses at most the items in flight
+			m.playlistState.Record(msg)
+			saveErr = m.jobs.Save(m.playlistState)
+		}
+		m.playlistCursor = min(m.playlistCursor, max(len(m.playlistActive)-1, 0))
+		if msg.Skipped && msg.SkipReason == youtube.SkipByUser {
+			m.playlistSkipped++
+			m.message = fmt.Sprintf("↷ Skipped: %s (%d/%d)", msg.Title, msg.Current, msg.Total)
+		} else if msg.Skipped {
+			m.playlistSkipped++
+			m.message = fmt.Sprintf("↷ Already downloaded: %s (%d/%d)", msg.Title, msg.Current, msg.Total)
+		} else if msg.Success && msg.Warning != "" {
+			m.playlistSuccess++
+			m.message = fmt.Sprintf("⚠ Downloaded: %s (%d/%d): %s", msg.Title, msg.Current, msg.Total, msg.Warning)
+		} else if msg.Success {
 			m.playlistSuccess++
 			m.message = fmt.Sprintf("✓ Downloaded: %s (%d/%d)", msg.Title, msg.Current, msg.Total)
 		} else {
@@ -118,32 +243,144 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.playlistFailedItems = append(m.playlistFailedItems, failedMsg)
 			m.message = fmt.Sprintf("✗ Failed: %s (%d/%d)", msg.Title, msg.Current, msg.Total)
 		}
-		// Continue downloading next item with accumulated counts
-		return m, youtube.DownloadNextPlaylistItem(m.playlistItems, msg.Current, m.playlistSuccess, m.playlistFailed, m.playlistFailedItems)
+		if saveErr != nil {
+			m.message += "\n  ⚠ Progress can't be resumed: " + saveErr.Error()
+		}
+		return m, msg.Download.Next()
 
 	case youtube.PlaylistDownloadCompleteMsg:
+		if msg.Download != m.playlistDownload {
+			return m, nil
+		}
+		m.playlistDownload = nil
+		// The summary screen shows the outcome
+		m.message = ""
+		m.screen = ScreenPlaylistSummary
+		m.summaryCursor = 0
+		m.summaryNotice = ""
 		if msg.Err != nil {
-			m.message = fmt.Sprintf("Playlist download failed: %s", msg.Err.Error())
-		} else {
-			m.message = fmt.Sprintf("✓ Playlist download complete! Success: %d, Failed: %d", msg.Success, msg.Failed)
-			if msg.Failed > 0 && len(msg.FailedItems) > 0 {
-				m.message += "\n\nFailed downloads:\n"
-				for _, item := range msg.FailedItems {
-					m.message += fmt.Sprintf("  • %s\n", item)
-				}
+			m.summaryNotice = "Playlist download failed: " + msg.Err.Error()
+		} else if msg.Aborted && m.playlistState != nil {
+			m.summaryNotice = fmt.Sprintf("Aborted, %d songs were not downloaded", m.playlistState.Count(resume.StatusPending))
+		}
+		// The finished job stays in memory for the summary screen
+		if m.playlistState != nil {
+			if err := m.jobs.Remove(m.playlistState.ID); err != nil {
+				m.summaryNotice = strings.TrimSpace(m.summaryNotice + "\n  ⚠ The saved job couldn't be cleared: " + err.Error())
 			}
 		}
-		m.screen = ScreenMenu
-		m.playlistItems = nil
-		m.playlistProgress = 0
-		m.playlistTotal = 0
+		m.playlistActive = nil
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// startPlaylist resets the playlist counters and downloads items through
+// the worker pool on the playlist downloading screen. With album set, the
+// items are saved as its tracks. skipArchived skips items that are already
+// in the download archive.
+func (m *Model) startPlaylist(name string, items []youtube.SearchResult, album *youtube.Album, skipArchived bool) tea.Cmd {
+	skipArchived = skipArchived && m.skipArchived
+	state := resume.NewJob(name, items, m.selectedOptions(), skipArchived)
+	state.Album = album
+	return m.runPlaylist(state)
+}
+
+// resumePlaylist continues a saved job with the items that didn't finish
+func (m *Model) resumePlaylist(state *resume.Job) tea.Cmd {
+	m.message = fmt.Sprintf("Resuming %q: %d songs left", state.Title(), len(state.Unfinished()))
+	return m.runPlaylist(state)
+}
+
+// runPlaylist downloads the unfinished items of state on the playlist
+// downloading screen, carrying over the counts of items already done
+func (m *Model) runPlaylist(state *resume.Job) tea.Cmd {
+	if err := m.jobs.Save(state); err != nil {
+		m.message = strings.TrimSpace(m.message + "\n  ⚠ Progress can't be resumed: " + err.Error())
+	}
+	m.playlistState = state
+	m.playlistName = state.Name
+	m.playlistItems = state.SearchResults()
+	m.playlistOptions = state.Options
+	m.playlistAlbum = state.Album
+	m.playlistTotal = len(state.Items)
+	m.playlistSuccess = state.Count(resume.StatusDone)
+	m.playlistSkipped = state.Count(resume.StatusSkipped)
+	m.playlistProgress = m.playlistSuccess + m.playlistSkipped
+	m.playlistFailed = 0
+	m.playlistFailedItems = []string{}
+	m.playlistActive = nil
+	m.playlistCursor = 0
+	m.removeResumable(state.ID)
+
+	// Cancelling goes back to where the download was started from
+	m.playlistReturn = m.screen
+	if m.screen == ScreenLoading {
+		m.playlistReturn = ScreenPlaylistInput
+	}
+	m.screen = ScreenPlaylistDownloading
+
+	job := m.playlistJob(state.SkipArchived)
+	job.Indexes = state.Unfinished()
+	m.playlistDownload = youtube.StartPlaylistDownload(context.Background(), m.backend, job)
+	return m.playlistDownload.Next()
+}
+
+// awaitsMetadata reports whether fetched metadata is still wanted: the
+// user may have left the details screen or moved on to another video
+func (m Model) awaitsMetadata(md *youtube.VideoMetadata) bool {
+	switch m.screen {
+	case ScreenLoading:
+		return true
+	case ScreenDetails, ScreenTags:
+		return md == nil || m.selected != nil && m.selected.ID == md.ID
+	}
+	return false
+}
+
+// removeResumable drops the job with the given ID from the menu's resume list
+func (m *Model) removeResumable(id string) {
+	for i, job := range m.resumable {
+		if job.ID == id {
+			m.resumable = append(m.resumable[:i:i], m.resumable[i+1:]...)
+			return
+		}
+	}
+}
+
+// discardResumable forgets the saved job at position i of the menu's resume
+// list. A job that can't be deleted stays on the list.
+func (m *Model) discardResumable(i int) {
+	if err := m.jobs.Remove(m.resumable[i].ID); err != nil {
+		m.menuNotice = "Couldn't discard the job: " + err.Error()
+		return
+	}
+	m.resumable = append(m.resumable[:i:i], m.resumable[i+1:]...)
+}
+
+// markID adds id to the selection set, creating it if needed
+func markID(marked map[string]bool, id string) map[string]bool {
+	if marked == nil {
+		marked = map[string]bool{}
+	}
+	marked[id] = true
+	return marked
+}
+
+// removeActiveItem returns items without the entry for the given playlist index
+func removeActiveItem(items []activeItem, index int) []activeItem {
+	kept := make([]activeItem, 0, len(items))
+	for _, item := range items {
+		if item.index != index {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
 func (m Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.menuNotice = ""
 	switch msg.String() {
 	case "ctrl+c", "q":
 		return m, tea.Quit
@@ -152,10 +389,24 @@ func (m Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.menuCursor--
 		}
 	case "down", "j":
-		if m.menuCursor < 2 {
+		if m.menuCursor < len(menuOptions(m))-1 {
 			m.menuCursor++
 		}
+	case "Q":
+		return m.openQueue()
+	case "x":
+		// Discard the unfinished job under the cursor
+		if i := m.menuCursor - len(menuActions); i >= 0 {
+			m.discardResumable(i)
+			m.menuCursor = min(m.menuCursor, len(menuOptions(m))-1)
+		}
 	case "enter":
+		if i := m.menuCursor - len(menuActions); i >= 0 {
+			m.menuCursor = 0
+			return m, m.resumePlaylist(m.resumable[i])
+		}
+		m.resetFormat()
+		m.message = ""
 		if m.menuCursor == 0 {
 			// Search music
 			m.screen = ScreenSearchInput
@@ -164,10 +415,12 @@ func (m Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Download from URL
 			m.screen = ScreenURLInput
 			m.textInput = ""
-		} else {
+		} else if m.menuCursor == 2 {
 			// Download from playlist
 			m.screen = ScreenPlaylistInput
 			m.textInput = ""
+		} else {
+			return m.openQueue()
 		}
 		return m, nil
 	}
@@ -191,9 +444,9 @@ func (m Model) updateSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		if m.textInput != "" {
 			m.searchQuery = m.textInput
-			m.searchLimit = 20 // Reset to 20 for new search
+			m.searchLimit = m.searchPageSize // Reset for new search
 			m.screen = ScreenSearch
-			return m, youtube.SearchYouTube(m.searchQuery, m.searchLimit)
+			return m, youtube.SearchYouTube(m.beginFetch(ScreenSearchInput), m.backend, m.searchQuery, m.searchLimit)
 		}
 		return m, nil
 	case "backspace":
@@ -234,7 +487,8 @@ func (m Model) updateURLInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			m.fromURL = true
 			m.screen = ScreenLoading
-			return m, youtube.FetchMetadata(videoID)
+			m.message = ""
+			return m, youtube.FetchMetadata(m.beginFetch(ScreenURLInput), m.backend, videoID)
 		}
 		return m, nil
 	case "backspace":
@@ -258,12 +512,78 @@ func (m Model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	case "esc":
 		// Go back to main menu
+		m.stopRadio()
 		m.screen = ScreenMenu
+		m.message = ""
 		m.results = nil
+		m.marked = nil
 		m.cursor = 0
 		m.searchQuery = ""
-		m.searchLimit = 20
+		m.searchLimit = m.searchPageSize
 		return m, nil
+	case "Q":
+		return m.openQueue()
+	case "r":
+		// Radio mode plays through the results from the cursor
+		if m.radio && !m.skim {
+			m.stopRadio()
+			m.message = "Radio stopped"
+			return m, nil
+		}
+		m.stopRadio()
+		return m, m.playRadio(min(m.cursor, len(m.results)-1))
+	case "n":
+		if m.radio {
+			return m, m.playRadio(m.radioIndex + 1)
+		}
+	case "p":
+		if m.radio && m.radioIndex > 0 {
+			return m, m.playRadio(m.radioIndex - 1)
+		}
+	case "s":
+		// Skim mode plays a snippet of each result from the cursor
+		if m.radio && m.skim {
+			m.stopRadio()
+			m.message = "Skim stopped"
+			return m, nil
+		}
+		return m, m.startSkim(min(m.cursor, len(m.results)-1))
+	case "d":
+		return m, m.queueRadioTrack()
+	case " ", "space":
+		if m.radio && m.skim {
+			// Skimming stops on the marked result
+			m.markSkimmed()
+			return m, nil
+		}
+		// Toggle selection of the result under the cursor
+		if m.cursor < len(m.results) {
+			id := m.results[m.cursor].ID
+			if m.marked[id] {
+				delete(m.marked, id)
+			} else {
+				m.marked = markID(m.marked, id)
+			}
+		}
+	case "a":
+		// Select all, or clear the selection if everything is selected
+		if len(m.markedResults()) == len(m.results) {
+			m.marked = nil
+		} else {
+			for _, r := range m.results {
+				m.marked = markID(m.marked, r.ID)
+			}
+		}
+	case "D":
+		// Download every selected result like a playlist
+		items := m.markedResults()
+		if len(items) == 0 {
+			return m, nil
+		}
+		m.marked = nil
+		m.message = fmt.Sprintf("Downloading %d selected songs...", len(items))
+		// Explicitly selected songs are downloaded even if owned
+		return m, m.startPlaylist("", items, nil, false)
 	case "up", "k":
 		if m.cursor > 0 {
 			m.cursor--
@@ -276,10 +596,10 @@ func (m Model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Check if "Load more" option is selected
 		if m.cursor == len(m.results) {
 			// Load more results
-			m.searchLimit += 20
+			m.searchLimit += m.searchPageSize
 			m.cursor = 0 // Reset cursor
 			m.screen = ScreenSearch
-			return m, youtube.SearchYouTube(m.searchQuery, m.searchLimit)
+			return m, youtube.SearchYouTube(m.beginFetch(ScreenResults), m.backend, m.searchQuery, m.searchLimit)
 		}
 
 		// Regular result selected
@@ -288,21 +608,20 @@ func (m Model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 			// Create partial metadata from search result
 			m.selected = &youtube.VideoMetadata{
-				Title: selected.Title,
-				ID:    selected.ID,
+				Title:   selected.Title,
+				ID:      selected.ID,
+				Channel: selected.Channel,
 			}
+			m.tags = nil
+			m.resetFormat()
 
-			// Start preview immediately
-			url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", selected.ID)
-			cmd := exec.Command("mpv", "--no-video", "--ytdl-format=bestaudio", url)
-			m.previewCmd = cmd
-			go cmd.Run()
-			m.previewing = true
-			m.message = "Playing preview... (press 's' to stop)"
+			// Start preview immediately, replacing the radio or skim
+			m.stopRadio()
+			preview := m.startPreview(selected.ID)
 
 			// Go to details screen and fetch full metadata in background
 			m.screen = ScreenDetails
-			return m, youtube.FetchMetadata(selected.ID)
+			return m, tea.Batch(preview, youtube.FetchMetadata(m.beginFetch(ScreenResults), m.backend, selected.ID))
 		}
 	}
 	return m, nil
@@ -331,9 +650,18 @@ func (m Model) updatePlaylistInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			m.screen = ScreenLoading
 			m.message = "Fetching playlist..."
-			return m, youtube.FetchPlaylistItems(playlistID)
+			return m, youtube.FetchPlaylistItems(m.beginFetch(ScreenPlaylistInput), m.backend, playlistID)
 		}
 		return m, nil
+	case "tab":
+		m.format = m.format.Next()
+		return m, nil
+	case "shift+tab":
+		m.quality = m.quality.Next()
+		return m, nil
+	case "ctrl+a":
+		m.albumMode = !m.albumMode
+		return m, nil
 	case "backspace":
 		if len(m.textInput) > 0 {
 			m.textInput = m.textInput[:len(m.textInput)-1]
@@ -350,16 +678,12 @@ func (m Model) updatePlaylistInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q":
-		if m.previewing && m.previewCmd != nil {
-			m.previewCmd.Process.Kill()
-		}
+		m.stopPreview()
 		return m, tea.Quit
 	case "esc":
-		if m.previewing && m.previewCmd != nil {
-			m.previewCmd.Process.Kill()
-			m.previewing = false
-			m.previewCmd = nil
-		}
+		m.stopPreview()
+		// Stop fetching full metadata in the background
+		m.cancelFetch()
 		if m.fromURL {
 			m.screen = ScreenMenu
 			m.fromURL = false
@@ -367,35 +691,316 @@ func (m Model) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.screen = ScreenResults
 		}
 		m.selected = nil
+		m.tags = nil
 		m.message = ""
 		return m, nil
+	case "t":
+		return m.openTags()
 	case "p":
-		if !m.previewing {
-			url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", m.selected.ID)
-			cmd := exec.Command("mpv", "--no-video", "--ytdl-format=bestaudio", url)
-			m.previewCmd = cmd
-			go cmd.Run()
-			m.previewing = true
-			m.message = "Playing preview... (press 's' to stop)"
+		if m.preview == nil {
+			return m, m.startPreview(m.selected.ID)
 		}
 		return m, nil
 	case "s":
-		if m.previewing && m.previewCmd != nil {
-			m.previewCmd.Process.Kill()
-			m.previewing = false
-			m.previewCmd = nil
+		if m.preview != nil {
+			m.stopPreview()
 			m.message = "Preview stopped"
 		}
 		return m, nil
+	case " ", "space":
+		if m.preview != nil {
+			m.controlPreview(m.preview.TogglePause())
+			m.previewStatus.Paused = !m.previewStatus.Paused
+		}
+		return m, nil
+	case "left", "h":
+		if m.preview != nil {
+			m.controlPreview(m.preview.Seek(-previewSeekStep))
+		}
+		return m, nil
+	case "right", "l":
+		if m.preview != nil {
+			m.controlPreview(m.preview.Seek(previewSeekStep))
+		}
+		return m, nil
+	case "+", "=":
+		if m.preview != nil {
+			m.controlPreview(m.preview.AddVolume(previewVolumeStep))
+		}
+		return m, nil
+	case "-":
+		if m.preview != nil {
+			m.controlPreview(m.preview.AddVolume(-previewVolumeStep))
+		}
+		return m, nil
+	case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		// Digits jump to 0%, 10%, ... 90% of the song
+		if m.preview != nil {
+			m.controlPreview(m.preview.SeekPercent(float64(msg.String()[0]-'0') * 10))
+		}
+		return m, nil
+	case "f":
+		m.format = m.format.Next()
+		return m, nil
+	case "b":
+		m.quality = m.quality.Next()
+		return m, nil
+	case "y":
+		m.lyrics = !m.lyrics
+		return m, nil
 	case "d":
-		if m.previewing && m.previewCmd != nil {
-			m.previewCmd.Process.Kill()
-			m.previewing = false
-			m.previewCmd = nil
-		}
-		m.downloading = true
-		m.screen = ScreenDownloading
-		return m, youtube.DownloadVideo(m.selected.ID, m.selected.Title)
+		// Downloads run in the background so browsing can continue
+		cmd := m.enqueue(m.downloadRequest())
+		m.message = fmt.Sprintf("Added to download queue (%d jobs) • Q to view queue", len(m.queue))
+		return m, cmd
+	case "Q":
+		return m.openQueue()
+	}
+	return m, nil
+}
+
+// updateFetching handles keys while a search, metadata or playlist lookup
+// is running. esc and ctrl+c cancel it and return to where it was started.
+func (m Model) updateFetching(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		if m.fetchCancel == nil {
+			return m, tea.Quit
+		}
+		m.cancelFetch()
+		if m.screen == ScreenSearch && m.fetchReturn == ScreenResults {
+			// Load more was cancelled, keep the current page size
+			m.searchLimit -= m.searchPageSize
+		}
+		m.fromURL = false
+		m.screen = m.fetchReturn
+		m.message = "Cancelled"
+	}
+	return m, nil
+}
+
+// updatePlaylistDownloading handles keys while a playlist downloads. esc
+// and ctrl+c cancel it; what finished so far is kept for resuming.
+func (m Model) updatePlaylistDownloading(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		if m.playlistDownload == nil {
+			return m, nil
+		}
+		m.playlistDownload.Cancel()
+		m.playlistDownload = nil
+		m.playlistActive = nil
+		if m.playlistState != nil {
+			m.resumable = append(m.resumable, m.playlistState)
+			m.playlistState = nil
+		}
+		m.screen = m.playlistReturn
+		m.message = "Playlist download cancelled, resume it from the menu"
+		// A retry started from the summary can't go back to it, the job
+		// now waits on the menu
+		if m.screen == ScreenPlaylistSummary {
+			m.screen = ScreenMenu
+		}
+		if m.screen == ScreenMenu {
+			m.menuNotice = m.message
+		}
+	case "up", "k":
+		if m.playlistCursor > 0 {
+			m.playlistCursor--
+		}
+	case "down", "j":
+		if m.playlistCursor < len(m.playlistActive)-1 {
+			m.playlistCursor++
+		}
+	case "p", " ", "space":
+		// Pausing lets the songs in flight finish but starts no new ones
+		if m.playlistDownload == nil {
+			return m, nil
+		}
+		if m.playlistDownload.Paused() {
+			m.playlistDownload.Resume()
+			m.message = "Resumed"
+		} else {
+			m.playlistDownload.Pause()
+			m.message = "Pausing after the songs in progress..."
+		}
+	case "s":
+		if m.playlistDownload == nil || m.playlistCursor >= len(m.playlistActive) {
+			return m, nil
+		}
+		item := m.playlistActive[m.playlistCursor]
+		if m.playlistDownload.Skip(item.index) {
+			m.message = "Skipping " + item.title + "..."
+		}
+	case "x":
+		if m.playlistDownload == nil {
+			return m, nil
+		}
+		m.playlistDownload.Abort()
+		m.message = "Aborting, waiting for the songs in progress to finish..."
+	}
+	return m, nil
+}
+
+func (m Model) updatePlaylistSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	state := m.playlistState
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc", "enter":
+		m.screen = ScreenMenu
+		m.playlistState = nil
+		m.playlistItems = nil
+		m.playlistProgress = 0
+		m.playlistTotal = 0
+		m.message = ""
+	case "up", "k":
+		if m.summaryCursor > 0 {
+			m.summaryCursor--
+		}
+	case "down", "j":
+		if m.summaryCursor < len(state.Items)-1 {
+			m.summaryCursor++
+		}
+	case "r":
+		// Failed and aborted items are unfinished, so resuming the job
+		// downloads them again
+		if len(state.Unfinished()) == 0 {
+			m.summaryNotice = "Nothing to retry"
+			return m, nil
+		}
+		return m, m.resumePlaylist(state)
+	case "e":
+		if state.Count(resume.StatusFailed) == 0 {
+			m.summaryNotice = "No failures to export"
+			return m, nil
+		}
+		dir := m.playlistOptions.OutputDir
+		if dir == "" {
+			dir = "."
+		}
+		reportPath, listPath, err := state.ExportFailures(dir)
+		if err != nil {
+			m.summaryNotice = "Export failed: " + err.Error()
+		} else {
+			m.summaryNotice = fmt.Sprintf("Exported %s and %s (download again with: music-download batch %s)",
+				reportPath, listPath, listPath)
+		}
+	}
+	return m, nil
+}
+
+// openQueue shows the Queue screen, remembering where to return to
+func (m Model) openQueue() (tea.Model, tea.Cmd) {
+	m.queueReturn = m.screen
+	m.screen = ScreenQueue
+	if m.queueCursor >= len(m.queue) {
+		m.queueCursor = max(len(m.queue)-1, 0)
+	}
+	return m, nil
+}
+
+func (m Model) updateQueue(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.screen = m.queueReturn
+		return m, nil
+	case "up", "k":
+		if m.queueCursor > 0 {
+			m.queueCursor--
+		}
+	case "down", "j":
+		if m.queueCursor < len(m.queue)-1 {
+			m.queueCursor++
+		}
+	case "shift+up", "K":
+		m.moveJob(-1)
+	case "shift+down", "J":
+		m.moveJob(1)
+	case "x":
+		m.cancelJob()
+	case "r":
+		return m, m.retryJob()
+	case "c":
+		m.clearFinishedJobs()
+	case "enter":
+		if m.queueCursor < len(m.queue) {
+			m.queueFocus = m.queue[m.queueCursor].id
+			m.screen = ScreenDownloading
+		}
 	}
 	return m, nil
 }
+
+func (m Model) updateDownloading(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		// The download keeps running in the background
+		m.screen = ScreenQueue
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "x":
+		if job := m.jobByID(m.queueFocus); job != nil && job.status == jobRunning {
+			job.download.Cancel()
+		}
+	}
+	return m, nil
+}
+
+// Preview controls step sizes
+const (
+	previewSeekStep   = 10 // seconds
+	previewVolumeStep = 5  // percent
+)
+
+// startPreview plays videoID with the configured player in the background.
+// The returned command polls mpv for the playback position.
+func (m *Model) startPreview(videoID string) tea.Cmd {
+	return m.previewStarted(m.previews.Play(youtube.VideoURL(videoID)))
+}
+
+// previewStarted records a preview started by the supervisor
+func (m *Model) previewStarted(p *player.Player, wait tea.Cmd, err error) tea.Cmd {
+	if err != nil {
+		m.preview = nil
+		m.message = "Preview failed: " + err.Error()
+		return nil
+	}
+	m.preview = p
+	m.previewStatus = player.Status{}
+	m.message = "Playing preview... (press 's' to stop)"
+	if !p.Controllable() {
+		return wait
+	}
+	return tea.Batch(wait, pollPreview(p))
+}
+
+// stopPreview kills the running preview, if any
+func (m *Model) stopPreview() {
+	m.previews.Stop()
+	m.preview = nil
+}
+
+// controlPreview reports a failed playback control
+func (m *Model) controlPreview(err error) {
+	if err != nil {
+		m.message = err.Error()
+	}
+}
+
+// previewStatusMsg carries the playback state of a running preview
+type previewStatusMsg struct {
+	player *player.Player
+	status player.Status
+	err    error
+}
+
+// pollPreview reads the player's playback state after a short delay
+func pollPreview(p *player.Player) tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		status, err := p.Status()
+		return previewStatusMsg{player: p, status: status, err: err}
+	})
+}
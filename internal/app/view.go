@@ -2,13 +2,26 @@ package app
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	"github.com/adelapazborrero/music_download/internal/resume"
 	"github.com/adelapazborrero/music_download/internal/ui"
 	"github.com/adelapazborrero/music_download/internal/utils"
+	"github.com/adelapazborrero/music_download/internal/youtube"
 )
 
-// View renders the appropriate screen based on current state
+// View renders the appropriate screen based on current state, followed by
+// the download queue status line
 func (m Model) View() string {
+	s := screenView(m)
+	if line := queueStatusLine(m); line != "" {
+		s += "\n" + ui.StatusStyle.Render(line) + "\n"
+	}
+	return s
+}
+
+func screenView(m Model) string {
 	switch m.screen {
 	case ScreenMenu:
 		return menuView(m)
@@ -23,23 +36,43 @@ func (m Model) View() string {
 	case ScreenResults:
 		return resultsView(m)
 	case ScreenLoading:
-		return loadingView()
+		return loadingView(m)
 	case ScreenDetails:
 		return detailsView(m)
 	case ScreenDownloading:
 		return downloadingView(m)
 	case ScreenPlaylistDownloading:
 		return playlistDownloadingView(m)
+	case ScreenQueue:
+		return queueView(m)
+	case ScreenTags:
+		return tagsView(m)
+	case ScreenPlaylistSummary:
+		return playlistSummaryView(m)
 	}
 	return ""
 }
 
+// menuActions are the fixed main menu entries; unfinished playlist jobs
+// are listed after them
+var menuActions = []string{"Search music", "Download from URL", "Download from playlist", "Download queue"}
+
+// menuOptions returns the main menu entries, including one per job that
+// can be resumed
+func menuOptions(m Model) []string {
+	options := append([]string{}, menuActions...)
+	for _, job := range m.resumable {
+		done := job.Count(resume.StatusDone) + job.Count(resume.StatusSkipped)
+		options = append(options, fmt.Sprintf("Resume %q (%d/%d done)", job.Title(), done, len(job.Items)))
+	}
+	return options
+}
+
 func menuView(m Model) string {
 	s := ui.TitleStyle.Render("Music Download") + "\n\n"
 	s += "  What would you like to do?\n\n"
 
-	options := []string{"Search music", "Download from URL", "Download from playlist"}
-	for i, option := range options {
+	for i, option := range menuOptions(m) {
 		cursor := "  "
 		if m.menuCursor == i {
 			cursor = "> "
@@ -49,7 +82,15 @@ func menuView(m Model) string {
 		}
 	}
 
-	s += ui.HelpStyle.Render("\nup/k up • down/j down • enter select • q quit")
+	if m.menuNotice != "" {
+		s += "\n  " + m.menuNotice + "\n"
+	}
+
+	help := "\nup/k up • down/j down • enter select"
+	if len(m.resumable) > 0 {
+		help += " • x discard unfinished job"
+	}
+	s += ui.HelpStyle.Render(help + " • Q queue • q quit")
 	return s
 }
 
@@ -57,6 +98,9 @@ func searchInputView(m Model) string {
 	s := ui.TitleStyle.Render("Search Music") + "\n\n"
 	s += "  Enter search terms:\n\n"
 	s += fmt.Sprintf("  > %s_\n", m.textInput)
+	if m.message != "" {
+		s += "\n  " + m.message + "\n"
+	}
 	s += ui.HelpStyle.Render("\nenter submit • esc back • ctrl+c quit")
 	return s
 }
@@ -73,11 +117,15 @@ func urlInputView(m Model) string {
 }
 
 func searchingView(query string) string {
-	return fmt.Sprintf("\nSearching YouTube for: %s\n\n", query)
+	return fmt.Sprintf("\nSearching YouTube for: %s\n\n", query) + ui.HelpStyle.Render("esc cancel")
 }
 
-func loadingView() string {
-	return "\nLoading video details...\n\n"
+func loadingView(m Model) string {
+	s := "\nLoading video details...\n\n"
+	if m.message != "" {
+		s = "\n" + m.message + "\n\n"
+	}
+	return s + ui.HelpStyle.Render("esc cancel")
 }
 
 func resultsView(m Model) string {
@@ -85,11 +133,26 @@ func resultsView(m Model) string {
 
 	for i, result := range m.results {
 		cursor := "  "
+		mark := "[ ] "
+		if m.marked[result.ID] {
+			mark = "[x] "
+		}
+		owned := ""
+		if m.archive.Has(result.ID) {
+			owned = " " + ui.OwnedStyle.Render("✓ owned")
+		}
+		if m.radio && m.radioIndex == i {
+			playing := "♪ now playing"
+			if m.skim {
+				playing = "♪ skimming"
+			}
+			owned += " " + ui.NowPlayingStyle.Render(playing)
+		}
 		if m.cursor == i {
 			cursor = "> "
-			s += ui.SelectedStyle.Render(fmt.Sprintf("%s%s", cursor, result.Title)) + "\n"
+			s += ui.SelectedStyle.Render(fmt.Sprintf("%s%s%s", cursor, mark, result.Title)) + owned + "\n"
 		} else {
-			s += fmt.Sprintf("%s%s\n", cursor, result.Title)
+			s += fmt.Sprintf("%s%s%s%s\n", cursor, mark, result.Title, owned)
 		}
 	}
 
@@ -102,8 +165,28 @@ func resultsView(m Model) string {
 	} else {
 		s += fmt.Sprintf("\n%s%s\n", cursor, loadMoreText)
 	}
+	if m.message != "" {
+		s += "\n  " + m.message + "\n"
+	}
+	if m.radio && m.preview != nil && m.previewStatus.Duration > 0 {
+		status := m.previewStatus
+		s += fmt.Sprintf("  %s %s %s\n", utils.FormatDuration(int(status.Position)),
+			utils.ProgressBar(status.Position/status.Duration*100, 30), utils.FormatDuration(int(status.Duration)))
+	}
 
-	s += ui.HelpStyle.Render("\nup/k up • down/j down • enter select • esc menu • q quit")
+	help := "\nup/k up • down/j down • enter select • space mark • a mark all"
+	if n := len(m.markedResults()); n > 0 {
+		help += fmt.Sprintf(" • D download %d marked", n)
+	}
+	switch {
+	case m.radio && m.skim:
+		help += "\ns stop skim • space mark and stop here • n next • p previous • d download playing"
+	case m.radio:
+		help += "\nr stop radio • n next • p previous • d download playing"
+	default:
+		help += " • r radio • s skim"
+	}
+	s += ui.HelpStyle.Render(help + " • Q queue • esc menu • q quit")
 	return s
 }
 
@@ -136,28 +219,154 @@ func detailsView(m Model) string {
 		s += "  Views:    Loading...\n"
 	}
 
+	s += fmt.Sprintf("\n  Format:   %s\n", m.selectedOptions().FormatLabel())
+	s += fmt.Sprintf("  Lyrics:   %s\n", m.selectedOptions().LyricsLabel())
+	if m.tags != nil || m.selected.Channel != "" {
+		tags := m.downloadRequest().WrittenTags()
+		s += fmt.Sprintf("  Tags:     %s - %s\n", tags.Artist, tags.Title)
+		if m.tags != nil {
+			s += fmt.Sprintf("  Saved as: %s\n", m.downloadRequest().Destination())
+		}
+	}
+	if entry, ok := m.archive.Get(m.selected.ID); ok {
+		s += fmt.Sprintf("  Owned:    %s (%s)\n", entry.Path, entry.DownloadedAt.Format("2006-01-02"))
+	}
+
+	if m.preview != nil && m.preview.Controllable() {
+		s += "\n" + previewView(m) + "\n"
+	}
+
 	if m.message != "" {
 		s += "\n  " + m.message + "\n"
 	}
 
 	helpText := "\nup/k up • down/j down • enter select • q quit"
-	if m.previewing {
-		helpText = "\ns stop preview • d download • esc back • q quit"
+	if m.preview != nil && m.preview.Controllable() {
+		helpText = "\nspace pause • ←/→ seek 10s • 0-9 jump to 0-90% • +/- volume" +
+			"\ns stop preview • d download • t tags • f format • b quality • y lyrics • Q queue • esc back • q quit"
+	} else if m.preview != nil {
+		helpText = "\ns stop preview • d download • t tags • f format • b quality • y lyrics • Q queue • esc back • q quit"
 	} else {
-		helpText = "\np preview • d download • esc back • q quit"
+		helpText = "\np preview • d download • t tags • f format • b quality • y lyrics • Q queue • esc back • q quit"
 	}
 	s += ui.HelpStyle.Render(helpText)
 	return s
 }
 
+// previewView renders the preview's elapsed time against the song's
+// duration as a progress bar
+func previewView(m Model) string {
+	status := m.previewStatus
+	duration := float64(m.selected.Duration)
+	if duration <= 0 {
+		duration = status.Duration
+	}
+	if duration <= 0 {
+		return "  ▶ Loading preview..."
+	}
+
+	icon := "▶"
+	if status.Paused {
+		icon = "⏸"
+	}
+	return fmt.Sprintf("  %s %s %s %s • vol %.0f%%", icon,
+		utils.FormatDuration(int(status.Position)), utils.ProgressBar(status.Position/duration*100, 30),
+		utils.FormatDuration(int(duration)), status.Volume)
+}
+
 func downloadingView(m Model) string {
+	job := m.jobByID(m.queueFocus)
+	if job == nil {
+		return "Download no longer in queue"
+	}
+
 	s := ui.TitleStyle.Render("Downloading") + "\n\n"
-	s += fmt.Sprintf("  Title:    %s\n", m.selected.Title)
-	s += "\n"
-	s += "  Status:   Downloading and converting to MP3...\n"
-	s += "  Quality:  High-quality audio with cover art\n"
+	s += fmt.Sprintf("  Title:    %s\n", job.req.Title)
+	s += fmt.Sprintf("  Format:   %s\n", job.req.FormatLabel())
+	s += fmt.Sprintf("  Saving:   %s\n", job.req.Destination())
 	s += "\n"
-	s += "  Please wait, this may take a moment...\n"
+
+	switch job.status {
+	case jobRunning:
+		s += progressView(job.progress)
+	case jobDone:
+		s += "  ✓ Download complete! Saved to " + job.path + "\n"
+		if job.warning != "" {
+			s += "  ⚠ " + job.warning + "\n"
+		}
+	case jobFailed:
+		s += "  ✗ " + job.err.Error() + "\n"
+	default:
+		s += fmt.Sprintf("  Status:   %s\n", strings.ToUpper(job.status.String()[:1])+job.status.String()[1:])
+	}
+
+	help := "\nesc back to queue"
+	if job.status == jobRunning {
+		help = "\nx cancel • esc back to queue"
+	}
+	s += ui.HelpStyle.Render(help + " • q quit")
+	return s
+}
+
+func queueView(m Model) string {
+	s := ui.TitleStyle.Render("Download Queue") + "\n\n"
+	if len(m.queue) == 0 {
+		s += "  The queue is empty. Press d on a song to add it.\n"
+	}
+
+	for i, job := range m.queue {
+		line := fmt.Sprintf("%-10s %s", "["+job.status.String()+"]", utils.Truncate(job.req.Title, 50))
+		if job.status == jobRunning {
+			line += fmt.Sprintf(" %s %5.1f%%", utils.ProgressBar(job.progress.Percent, 15), job.progress.Percent)
+		}
+		if m.queueCursor == i {
+			s += ui.SelectedStyle.Render("> "+line) + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+
+	s += ui.HelpStyle.Render("\nenter details • K/J move • x cancel • r retry • c clear done • esc back • q quit")
+	return s
+}
+
+// activeItemView renders one in-flight playlist item as a compact progress line
+func activeItemView(item activeItem) string {
+	p := item.progress
+	stage := p.Stage
+	if stage == "" {
+		stage = "starting"
+	}
+	return fmt.Sprintf("%3d. %-40s %s %5.1f%% %s",
+		item.index+1, utils.Truncate(item.title, 40), utils.ProgressBar(p.Percent, 20), p.Percent, stage)
+}
+
+// progressView renders the live progress of the current download
+func progressView(p youtube.Progress) string {
+	if p.Stage == "" {
+		return "  Status:   Starting download...\n"
+	}
+	if p.Stage == youtube.StageRetrying {
+		return fmt.Sprintf("  Status:   Network error, retrying in %ds...\n", p.ETA)
+	}
+
+	s := fmt.Sprintf("  Status:   %s\n", strings.ToUpper(p.Stage[:1])+p.Stage[1:])
+	s += fmt.Sprintf("  %s %5.1f%%\n", utils.ProgressBar(p.Percent, 30), p.Percent)
+	if p.Stage != youtube.StageDownloading {
+		return s
+	}
+
+	details := utils.FormatBytes(p.Downloaded)
+	if p.Total > 0 {
+		details += " / " + utils.FormatBytes(p.Total)
+	}
+	if p.Speed > 0 {
+		details += " • " + utils.FormatBytes(int64(p.Speed)) + "/s"
+	}
+	if p.ETA >= 0 {
+		details += " • ETA " + utils.FormatDuration(p.ETA)
+	}
+	s += "  " + details + "\n"
 	return s
 }
 
@@ -165,10 +374,16 @@ func playlistInputView(m Model) string {
 	s := ui.TitleStyle.Render("Download from Playlist") + "\n\n"
 	s += "  Enter YouTube playlist URL:\n\n"
 	s += fmt.Sprintf("  > %s_\n", m.textInput)
+	s += fmt.Sprintf("\n  Format:   %s\n", m.selectedOptions().FormatLabel())
+	if m.albumMode {
+		s += "  Album:    on (tags, track numbers, one cover and folder)\n"
+	} else {
+		s += "  Album:    off\n"
+	}
 	if m.message != "" {
 		s += "\n  " + m.message + "\n"
 	}
-	s += ui.HelpStyle.Render("\nenter submit • esc back • ctrl+c quit")
+	s += ui.HelpStyle.Render("\nenter submit • tab format • shift+tab quality • ctrl+a album mode • esc back • ctrl+c quit")
 	return s
 }
 
@@ -179,14 +394,143 @@ func playlistDownloadingView(m Model) string {
 		s += fmt.Sprintf("  Progress:       %d/%d\n", m.playlistProgress, m.playlistTotal)
 		s += fmt.Sprintf("  Success:        %d\n", m.playlistSuccess)
 		s += fmt.Sprintf("  Failed:         %d\n", m.playlistFailed)
+		if m.playlistSkipped > 0 {
+			s += fmt.Sprintf("  Skipped:        %d\n", m.playlistSkipped)
+		}
 	}
 	s += "\n"
-	s += "  Status:   Downloading songs as MP3...\n"
-	s += "  Quality:  High-quality audio with cover art\n"
+	s += fmt.Sprintf("  Format:   %s\n", m.playlistOptions.FormatLabel())
+	if m.playlistAlbum != nil {
+		s += fmt.Sprintf("  Album:    %s\n", m.playlistAlbum.String())
+	}
+	s += fmt.Sprintf("  Saving:   %s\n", m.playlistDestination())
 	s += "\n"
+	if len(m.playlistActive) > 0 {
+		s += fmt.Sprintf("  In progress (%d workers):\n", m.playlistWorkers)
+		for i, item := range m.playlistActive {
+			if i == m.playlistCursor {
+				s += ui.SelectedStyle.Render("> "+activeItemView(item)) + "\n"
+			} else {
+				s += "  " + activeItemView(item) + "\n"
+			}
+		}
+		s += "\n"
+	}
 	if m.message != "" {
 		s += "  " + m.message + "\n\n"
 	}
-	s += "  Please wait, this may take several minutes...\n"
+
+	paused := m.playlistDownload != nil && m.playlistDownload.Paused()
+	switch {
+	case paused && len(m.playlistActive) == 0:
+		s += "  ⏸ Paused\n"
+	case paused:
+		s += "  ⏸ Paused, finishing the songs in progress...\n"
+	default:
+		s += "  Please wait, this may take several minutes...\n"
+	}
+
+	help := "\np pause"
+	if paused {
+		help = "\np resume"
+	}
+	if len(m.playlistActive) > 1 {
+		help += " • up/k down/j select song"
+	}
+	if len(m.playlistActive) > 0 {
+		help += " • s skip song"
+	}
+	s += ui.HelpStyle.Render(help + " • x abort the rest • esc cancel (finished songs are kept)")
+	return s
+}
+
+func playlistSummaryView(m Model) string {
+	state := m.playlistState
+	s := ui.TitleStyle.Render("Playlist Finished: "+state.Title()) + "\n\n"
+	s += fmt.Sprintf("  Success: %d • Failed: %d • Skipped: %d • Saved to %s\n\n",
+		m.playlistSuccess, m.playlistFailed, m.playlistSkipped, filepath.Dir(m.playlistDestination()))
+	groups := failureGroupsView(state)
+	s += groups
+
+	// Only a window of the list around the cursor fits on screen
+	rows := 15
+	if m.height > 0 {
+		rows = max(m.height-12-strings.Count(groups, "\n"), 5)
+	}
+	start := min(max(m.summaryCursor-rows/2, 0), max(len(state.Items)-rows, 0))
+	end := min(start+rows, len(state.Items))
+
+	for i := start; i < end; i++ {
+		item := state.Items[i]
+		var line string
+		switch item.Status {
+		case resume.StatusDone:
+			line = fmt.Sprintf("✓ %3d. %s → %s", i+1, utils.Truncate(item.Title, 40), item.Path)
+			if item.Warning != "" {
+				line += " ⚠ " + item.Warning
+			}
+		case resume.StatusSkipped:
+			reason := item.Reason
+			if reason == "" {
+				reason = youtube.SkipArchived
+			}
+			line = fmt.Sprintf("↷ %3d. %s (%s)", i+1, utils.Truncate(item.Title, 40), reason)
+		case resume.StatusFailed:
+			line = fmt.Sprintf("✗ %3d. %s [%s] %s", i+1, utils.Truncate(item.Title, 40), item.Category, item.Error)
+		default:
+			line = fmt.Sprintf("  %3d. %s (not downloaded)", i+1, utils.Truncate(item.Title, 40))
+		}
+		if m.summaryCursor == i {
+			s += ui.SelectedStyle.Render("> "+line) + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+	if len(state.Items) > rows {
+		s += fmt.Sprintf("\n  %d-%d of %d\n", start+1, end, len(state.Items))
+	}
+
+	if m.summaryNotice != "" {
+		s += "\n  " + m.summaryNotice + "\n"
+	}
+
+	help := "\nup/k up • down/j down"
+	if state.Count(resume.StatusPending) > 0 {
+		help += " • r download the rest"
+	} else if state.Count(resume.StatusFailed) > 0 {
+		help += " • r retry failed"
+	}
+	if state.Count(resume.StatusFailed) > 0 {
+		help += " • e export failures"
+	}
+	s += ui.HelpStyle.Render(help + " • enter/esc menu • q quit")
 	return s
 }
+
+// failureGroupsView lists the job's failed items grouped under their error
+// category, one line per category
+func failureGroupsView(state *resume.Job) string {
+	byCategory := map[youtube.ErrorCategory][]string{}
+	for _, item := range state.Items {
+		if item.Status == resume.StatusFailed {
+			category := youtube.ErrorCategory(item.Category)
+			if category == "" {
+				category = youtube.CategoryOther
+			}
+			byCategory[category] = append(byCategory[category], item.Title)
+		}
+	}
+	if len(byCategory) == 0 {
+		return ""
+	}
+
+	s := "  Failed downloads:\n"
+	for _, category := range youtube.ErrorCategories {
+		titles := byCategory[category]
+		if len(titles) == 0 {
+			continue
+		}
+		s += fmt.Sprintf("    %s (%d): %s\n", category.Label(), len(titles), utils.Truncate(strings.Join(titles, ", "), 60))
+	}
+	return s + "\n"
+}
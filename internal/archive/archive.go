@@ -0,0 +1,164 @@
+// Package archive records downloaded videos so they aren't fetched twice.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// Entry is a single downloaded video
+type Entry struct {
+	VideoID      string    `json:"id"`
+	Title        string    `json:"title"`
+	Path         string    `json:"path"`
+	Format       string    `json:"format"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Archive is an append-only JSON Lines file of downloaded videos. It is
+// safe for concurrent use; a nil *Archive is empty and ignores additions.
+type Archive struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+	// damaged counts the lines that couldn't be read
+	damaged int
+	// unterminated is set when the file doesn't end with a newline, e.g.
+	// after a crash mid-append, so the next entry starts a new line
+	unterminated bool
+}
+
+// Open loads the archive at path. A missing file is an empty archive.
+// Malformed lines, such as one cut short by a crash, are skipped and
+// counted by Damaged.
+func Open(path string) (*Archive, error) {
+	a := &Archive{path: path, entries: map[string]Entry{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	a.unterminated = len(data) > 0 && data[len(data)-1] != '\n'
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil || e.VideoID == "" {
+			a.damaged++
+			continue
+		}
+		a.entries[e.VideoID] = e
+	}
+	return a, nil
+}
+
+// Damaged returns the number of malformed lines skipped by Open
+func (a *Archive) Damaged() int {
+	if a == nil {
+		return 0
+	}
+	return a.damaged
+}
+
+// Path returns the archive's file name
+func (a *Archive) Path() string {
+	if a == nil {
+		return ""
+	}
+	return a.path
+}
+
+// Has reports whether videoID was downloaded before
+func (a *Archive) Has(videoID string) bool {
+	_, ok := a.Get(videoID)
+	return ok
+}
+
+// Get returns the archive entry for videoID
+func (a *Archive) Get(videoID string) (Entry, bool) {
+	if a == nil {
+		return Entry{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e, ok := a.entries[videoID]
+	return e, ok
+}
+
+// Add records e and appends it to the archive file
+func (a *Archive) Add(e Entry) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return fmt.Errorf("creating archive directory: %w", err)
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if a.unterminated {
+		line = append([]byte{'\n'}, line...)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	a.unterminated = false
+	a.entries[e.VideoID] = e
+	return nil
+}
+
+// Backend wraps a youtube.Backend and records every successful download
+type Backend struct {
+	youtube.Backend
+	Archive *Archive
+}
+
+// Wrap returns b recording its downloads into a
+func Wrap(b youtube.Backend, a *Archive) *Backend {
+	return &Backend{Backend: b, Archive: a}
+}
+
+// Download downloads through the wrapped backend and archives the result.
+// Failing to write the archive doesn't fail the download; it is returned as
+// a youtube.Warning instead.
+func (b *Backend) Download(ctx context.Context, req youtube.DownloadRequest, progress youtube.ProgressFunc) (string, error) {
+	path, err := b.Backend.Download(ctx, req, progress)
+	if err != nil && !youtube.IsWarning(err) {
+		return path, err
+	}
+
+	if archiveErr := b.Archive.Add(Entry{
+		VideoID:      req.VideoID,
+		Title:        req.Title,
+		Path:         path,
+		Format:       req.FormatName(),
+		DownloadedAt: time.Now(),
+	}); archiveErr != nil {
+		err = youtube.Warn(err, fmt.Errorf("not recorded in the download archive: %w", archiveErr))
+	}
+	return path, err
+}
@@ -0,0 +1,168 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+const (
+	entryA = `{"id":"aaaaaaaaaaa","title":"A","path":"A.mp3","format":"mp3","downloaded_at":"2024-01-01T00:00:00Z"}`
+	entryB = `{"id":"bbbbbbbbbbb","title":"B","path":"B.mp3","format":"mp3","downloaded_at":"2024-01-01T00:00:00Z"}`
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantIDs     []string
+		wantDamaged int
+	}{
+		{"entries", entryA + "\n" + entryB + "\n", []string{"aaaaaaaaaaa", "bbbbbbbbbbb"}, 0},
+		{"blank lines", "\n" + entryA + "\n\n  \n", []string{"aaaaaaaaaaa"}, 0},
+		{"line cut short by a crash", entryA + "\n" + entryB[:40], []string{"aaaaaaaaaaa"}, 1},
+		{"garbage and missing IDs", "garbage\n" + entryA + "\n{\"title\":\"no id\"}\n", []string{"aaaaaaaaaaa"}, 2},
+		{"empty file", "", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "archive.jsonl")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			a, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			for _, id := range tt.wantIDs {
+				if !a.Has(id) {
+					t.Errorf("Has(%q) = false", id)
+				}
+			}
+			if len(a.entries) != len(tt.wantIDs) {
+				t.Errorf("got %d entries, want %d", len(a.entries), len(tt.wantIDs))
+			}
+			if a.Damaged() != tt.wantDamaged {
+				t.Errorf("Damaged() = %d, want %d", a.Damaged(), tt.wantDamaged)
+			}
+		})
+	}
+}
+
+func TestOpenMissing(t *testing.T) {
+	a, err := Open(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if a.Has("aaaaaaaaaaa") || a.Damaged() != 0 {
+		t.Error("missing archive isn't empty")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"new file", ""},
+		{"after complete lines", entryA + "\n"},
+		{"after a line cut short", entryA + "\n" + entryB[:40]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "music", "archive.jsonl")
+			if tt.data != "" {
+				os.MkdirAll(filepath.Dir(path), 0o755)
+				if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Add(Entry{VideoID: "ccccccccccc", Title: "C"}); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if err := a.Add(Entry{VideoID: "ddddddddddd", Title: "D"}); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+
+			// The new entries survive a reload whatever came before them
+			reopened, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"ccccccccccc", "ddddddddddd"} {
+				if !a.Has(id) || !reopened.Has(id) {
+					t.Errorf("%s missing after Add", id)
+				}
+			}
+			if reopened.Damaged() != a.Damaged() {
+				t.Errorf("Damaged() after Add = %d, want %d", reopened.Damaged(), a.Damaged())
+			}
+		})
+	}
+}
+
+func TestNilArchive(t *testing.T) {
+	var a *Archive
+	if a.Has("aaaaaaaaaaa") || a.Damaged() != 0 || a.Path() != "" {
+		t.Error("nil archive isn't empty")
+	}
+	if err := a.Add(Entry{VideoID: "aaaaaaaaaaa"}); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	fake := youtube.NewFakeBackend()
+	fake.AddVideo(youtube.VideoMetadata{ID: "aaaaaaaaaaa", Title: "Song"})
+	fake.DownloadErrors["bbbbbbbbbbb"] = &youtube.DownloadError{Category: youtube.CategoryPrivate, Message: "private"}
+	dir := t.TempDir()
+
+	t.Run("records downloads", func(t *testing.T) {
+		a, _ := Open(filepath.Join(dir, "archive.jsonl"))
+		path, err := Wrap(fake, a).Download(context.Background(), youtube.DownloadRequest{VideoID: "aaaaaaaaaaa", Title: "Song"}, nil)
+		if err != nil {
+			t.Fatalf("Download() error = %v", err)
+		}
+		e, ok := a.Get("aaaaaaaaaaa")
+		if !ok || e.Path != path || e.Title != "Song" {
+			t.Errorf("archived %+v, %v; want path %q", e, ok, path)
+		}
+	})
+
+	t.Run("skips failures", func(t *testing.T) {
+		a, _ := Open(filepath.Join(dir, "archive.jsonl"))
+		if _, err := Wrap(fake, a).Download(context.Background(), youtube.DownloadRequest{VideoID: "bbbbbbbbbbb"}, nil); err == nil {
+			t.Fatal("Download() succeeded")
+		}
+		if a.Has("bbbbbbbbbbb") {
+			t.Error("failed download was archived")
+		}
+	})
+
+	t.Run("warns when the archive can't be written", func(t *testing.T) {
+		// A file takes the place of the archive's directory after it's opened
+		music := filepath.Join(dir, "music")
+		a, err := Open(filepath.Join(music, "archive.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(music, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		path, err := Wrap(fake, a).Download(context.Background(), youtube.DownloadRequest{VideoID: "aaaaaaaaaaa", Title: "Song"}, nil)
+		if !youtube.IsWarning(err) || path == "" {
+			t.Fatalf("Download() = %q, %v; want the path and a warning", path, err)
+		}
+		if !strings.Contains(err.Error(), "download archive") {
+			t.Errorf("warning %q doesn't mention the archive", err)
+		}
+	})
+}
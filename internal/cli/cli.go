@@ -0,0 +1,193 @@
+// Package cli implements the non-interactive subcommands used from scripts,
+// cron jobs and CI pipelines.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/adelapazborrero/music_download/internal/archive"
+	"github.com/adelapazborrero/music_download/internal/config"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0 // everything succeeded
+	ExitFailure  = 1 // the operation failed
+	ExitUsage    = 2 // invalid arguments or settings
+	ExitNotFound = 3 // no results, or the video/playlist doesn't exist
+	ExitPartial  = 4 // some playlist items failed
+	// ExitCancelled follows the shell convention for SIGINT (128+2)
+	ExitCancelled = 130 // interrupted with ctrl+c or SIGTERM
+)
+
+// command is a subcommand's entry point
+type command struct {
+	usage string
+	run   func(e *env, args []string) int
+}
+
+var commands = map[string]command{
+	"search":   {"search <query> [--json] [--search-limit N]", runSearch},
+	"download": {"download <url|id> [--json]", runDownload},
+	"playlist": {"playlist <url> [--json]", runPlaylist},
+	"info":     {"info <url|id> [--json]", runInfo},
+	"batch":    {"batch <file|-> [--json]", runBatch},
+}
+
+// IsCommand reports whether name is a known subcommand
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// env carries what every subcommand needs
+type env struct {
+	// ctx is cancelled on ctrl+c or SIGTERM, killing running yt-dlp calls
+	ctx     context.Context
+	cfg     *config.Config
+	backend youtube.Backend
+	archive *archive.Archive
+	json    bool
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// Run executes the subcommand named by args[0] and returns its exit code.
+// Settings flags are accepted after the subcommand as well.
+func Run(args []string, cfg *config.Config, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		return ExitUsage
+	}
+
+	e := &env{cfg: cfg, stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&e.json, "json", false, "print machine-readable JSON")
+	cfg.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: music-download %s\n\nFlags:\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	if err != nil {
+		return ExitUsage
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	if err := youtube.CheckTools("yt-dlp", "ffmpeg"); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	if e.archive, err = cfg.Archive(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	if n := e.archive.Damaged(); n > 0 {
+		fmt.Fprintf(stderr, "Warning: skipped %d damaged lines in %s\n", n, e.archive.Path())
+	}
+	e.backend = archive.Wrap(cfg.Backend(), e.archive)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer youtube.KillAll()
+	e.ctx = ctx
+	return cmd.run(e, positional)
+}
+
+// parseInterspersed parses flags that may appear anywhere among the
+// positional arguments, e.g. `search lofi beats --json`
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printJSON writes v as indented JSON to stdout
+func (e *env) printJSON(v any) int {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// fail reports err on stderr (or as JSON) and returns code. Errors caused
+// by an interrupt are reported as a cancellation instead.
+func (e *env) fail(code int, err error) int {
+	if e.ctx != nil && e.ctx.Err() != nil {
+		code, err = ExitCancelled, youtube.ErrCancelled
+	}
+	if e.json {
+		e.printJSON(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintln(e.stderr, "Error:", err)
+	}
+	return code
+}
+
+// failureCode returns the exit code for a failed fetch or download:
+// ExitNotFound when the video or playlist doesn't exist, else ExitFailure
+func failureCode(err error) int {
+	if youtube.CategoryOf(err) == youtube.CategoryRemoved {
+		return ExitNotFound
+	}
+	return ExitFailure
+}
+
+// skipArchived returns the job filter for items already in the archive,
+// or nil when skipping is disabled
+func (e *env) skipArchived() func(videoID string) bool {
+	if !e.cfg.SkipArchived {
+		return nil
+	}
+	return e.archive.Has
+}
+
+// usageError reports a missing or invalid argument
+func (e *env) usageError(format string, a ...any) int {
+	fmt.Fprintf(e.stderr, format+"\n", a...)
+	return ExitUsage
+}
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adelapazborrero/music_download/internal/config"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+const (
+	goodID    = "aaaaaaaaaaa"
+	privateID = "bbbbbbbbbbb"
+	warnID    = "ccccccccccc"
+	missingID = "ddddddddddd"
+)
+
+// testBackend returns a fake backend with a few videos and playlists
+func testBackend() *youtube.FakeBackend {
+	b := youtube.NewFakeBackend()
+	good := b.AddVideo(youtube.VideoMetadata{ID: goodID, Title: "Good Song", Channel: "Artist"})
+	private := b.AddVideo(youtube.VideoMetadata{ID: privateID, Title: "Private Song", Channel: "Artist"})
+	b.AddVideo(youtube.VideoMetadata{ID: warnID, Title: "Coverless Song", Channel: "Artist"})
+	b.DownloadErrors[privateID] = &youtube.DownloadError{Category: youtube.CategoryPrivate, Message: "Private video"}
+	b.DownloadErrors[missingID] = &youtube.DownloadError{Category: youtube.CategoryRemoved, Message: "Video unavailable"}
+	b.DownloadErrors[warnID] = &youtube.Warning{Err: errors.New("cover art: no thumbnail")}
+	b.SearchResults["lofi"] = []youtube.SearchResult{good, private}
+	b.Playlists["PLgood"] = youtube.Playlist{ID: "PLgood", Title: "Good", Items: []youtube.SearchResult{good}}
+	b.Playlists["PLmixed"] = youtube.Playlist{ID: "PLmixed", Title: "Mixed", Items: []youtube.SearchResult{good, private}}
+	b.Playlists["PLbad"] = youtube.Playlist{ID: "PLbad", Title: "Bad", Items: []youtube.SearchResult{private}}
+	b.Playlists["PLempty"] = youtube.Playlist{ID: "PLempty", Title: "Empty"}
+	return b
+}
+
+// testEnv returns an env running commands against b, as Run would set it up
+func testEnv(t *testing.T, ctx context.Context, b youtube.Backend) (*env, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.OutputDir = t.TempDir()
+	cfg.SkipArchived = false
+	var stdout, stderr bytes.Buffer
+	return &env{ctx: ctx, cfg: cfg, backend: b, stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}, &stdout, &stderr
+}
+
+func playlistURL(id string) string {
+	return "https://www.youtube.com/playlist?list=" + id
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"unknown command", []string{"fetch", "x"}, ExitUsage},
+		{"unknown flag", []string{"search", "--loud", "x"}, ExitUsage},
+		{"invalid setting", []string{"search", "--workers", "0", "x"}, ExitUsage},
+		{"bad flag value", []string{"download", "--retries", "many", goodID}, ExitUsage},
+		{"help", []string{"playlist", "--help"}, ExitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(tt.args, config.Default(), strings.NewReader(""), &stdout, &stderr); got != tt.want {
+				t.Errorf("Run(%q) = %d, want %d; stderr: %s", tt.args, got, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	batch := filepath.Join(t.TempDir(), "batch.txt")
+	os.WriteFile(batch, []byte(goodID+"\nhttps://youtu.be/"+privateID+"\n"), 0o644)
+	emptyBatch := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(emptyBatch, []byte("# nothing yet\n"), 0o644)
+
+	tests := []struct {
+		name string
+		run  func(e *env, args []string) int
+		args []string
+		ctx  context.Context
+		want int
+	}{
+		{"search", runSearch, []string{"lofi"}, nil, ExitOK},
+		{"search without query", runSearch, nil, nil, ExitUsage},
+		{"search without results", runSearch, []string{"silence"}, nil, ExitNotFound},
+		{"search cancelled", runSearch, []string{"lofi"}, cancelled, ExitCancelled},
+
+		{"info", runInfo, []string{goodID}, nil, ExitOK},
+		{"info of a bad URL", runInfo, []string{"not a url"}, nil, ExitUsage},
+		{"info of a missing video", runInfo, []string{missingID}, nil, ExitNotFound},
+
+		{"download", runDownload, []string{youtube.VideoURL(goodID)}, nil, ExitOK},
+		{"download with a warning", runDownload, []string{warnID}, nil, ExitOK},
+		{"download of two videos", runDownload, []string{goodID, privateID}, nil, ExitUsage},
+		{"download of a private video", runDownload, []string{privateID}, nil, ExitFailure},
+		{"download of a missing video", runDownload, []string{missingID}, nil, ExitNotFound},
+		{"download cancelled", runDownload, []string{goodID}, cancelled, ExitCancelled},
+
+		{"playlist", runPlaylist, []string{playlistURL("PLgood")}, nil, ExitOK},
+		{"playlist with failures", runPlaylist, []string{playlistURL("PLmixed")}, nil, ExitPartial},
+		{"playlist of failures", runPlaylist, []string{playlistURL("PLbad")}, nil, ExitFailure},
+		{"empty playlist", runPlaylist, []string{playlistURL("PLempty")}, nil, ExitNotFound},
+		{"missing playlist", runPlaylist, []string{playlistURL("PLmissing")}, nil, ExitNotFound},
+		{"playlist of a video URL", runPlaylist, []string{goodID}, nil, ExitUsage},
+		{"playlist cancelled", runPlaylist, []string{playlistURL("PLgood")}, cancelled, ExitCancelled},
+
+		{"batch", runBatch, []string{batch}, nil, ExitPartial},
+		{"empty batch", runBatch, []string{emptyBatch}, nil, ExitNotFound},
+		{"missing batch file", runBatch, []string{filepath.Join(t.TempDir(), "none.txt")}, nil, ExitUsage},
+	}
+	for _, tt := range tests {
+		for _, json := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/json=%v", tt.name, json), func(t *testing.T) {
+				ctx := tt.ctx
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				e, _, stderr := testEnv(t, ctx, testBackend())
+				e.json = json
+				if got := tt.run(e, tt.args); got != tt.want {
+					t.Errorf("exit code %d, want %d; stderr: %s", got, tt.want, stderr.String())
+				}
+			})
+		}
+	}
+}
+
+func TestDownloadWarning(t *testing.T) {
+	e, stdout, stderr := testEnv(t, context.Background(), testBackend())
+	if code := runDownload(e, []string{warnID}); code != ExitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(stdout.String()), ".mp3") {
+		t.Errorf("stdout = %q, want the saved path", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Warning: cover art: no thumbnail") {
+		t.Errorf("stderr = %q, want the warning", stderr.String())
+	}
+
+	e, stdout, _ = testEnv(t, context.Background(), testBackend())
+	e.json = true
+	runDownload(e, []string{warnID})
+	if !strings.Contains(stdout.String(), `"warning": "cover art: no thumbnail"`) {
+		t.Errorf("JSON output %s has no warning", stdout.String())
+	}
+}
+
+func TestFailureCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&youtube.DownloadError{Category: youtube.CategoryRemoved}, ExitNotFound},
+		{fmt.Errorf("download failed: %w", &youtube.DownloadError{Category: youtube.CategoryRemoved}), ExitNotFound},
+		{&youtube.DownloadError{Category: youtube.CategoryPrivate}, ExitFailure},
+		{errors.New("exit status 1"), ExitFailure},
+	}
+	for _, tt := range tests {
+		if got := failureCode(tt.err); got != tt.want {
+			t.Errorf("failureCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adelapazborrero/music_download/internal/utils"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// searchResultJSON is the JSON form of a search result
+type searchResultJSON struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func runSearch(e *env, args []string) int {
+	query := strings.Join(args, " ")
+	if query == "" {
+		return e.usageError("search: missing query")
+	}
+
+	results, err := e.backend.Search(e.ctx, query, e.cfg.SearchLimit)
+	if err != nil {
+		return e.fail(ExitFailure, fmt.Errorf("search failed: %w", err))
+	}
+	if len(results) == 0 {
+		return e.fail(ExitNotFound, fmt.Errorf("no results found"))
+	}
+
+	if e.json {
+		out := make([]searchResultJSON, 0, len(results))
+		for _, r := range results {
+			out = append(out, searchResultJSON{ID: r.ID, Title: r.Title, URL: youtube.VideoURL(r.ID)})
+		}
+		return e.printJSON(out)
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(e.stdout, "%s\t%s\n", r.ID, r.Title)
+	}
+	return ExitOK
+}
+
+func runInfo(e *env, args []string) int {
+	if len(args) != 1 {
+		return e.usageError("info: expected exactly one URL or video ID")
+	}
+	videoID := youtube.ExtractVideoID(args[0])
+	if videoID == "" {
+		return e.usageError("info: invalid YouTube URL %q", args[0])
+	}
+
+	metadata, err := e.backend.Metadata(e.ctx, videoID)
+	if err != nil {
+		return e.fail(failureCode(err), fmt.Errorf("failed to fetch metadata: %w", err))
+	}
+
+	if e.json {
+		return e.printJSON(metadata)
+	}
+
+	fmt.Fprintf(e.stdout, "Title:    %s\n", metadata.Title)
+	fmt.Fprintf(e.stdout, "Channel:  %s\n", metadata.Channel)
+	fmt.Fprintf(e.stdout, "Duration: %s\n", utils.FormatDuration(metadata.Duration))
+	fmt.Fprintf(e.stdout, "Views:    %s\n", utils.FormatNumber(metadata.ViewCount))
+	fmt.Fprintf(e.stdout, "ID:       %s\n", metadata.ID)
+	fmt.Fprintf(e.stdout, "URL:      %s\n", youtube.VideoURL(metadata.ID))
+	return ExitOK
+}
+
+// downloadJSON is the JSON form of a finished download
+type downloadJSON struct {
+	ID       string `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Warning  string `json:"warning,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+func runDownload(e *env, args []string) int {
+	if len(args) != 1 {
+		return e.usageError("download: expected exactly one URL or video ID")
+	}
+	videoID := youtube.ExtractVideoID(args[0])
+	if videoID == "" {
+		return e.usageError("download: invalid YouTube URL %q", args[0])
+	}
+
+	req := youtube.DownloadRequest{VideoID: videoID, DownloadOptions: e.cfg.DownloadOptions()}
+	path, err := e.backend.Download(e.ctx, req, e.progressReporter(videoID))
+	var warning string
+	if youtube.IsWarning(err) {
+		warning, err = err.Error(), nil
+	}
+	if err != nil {
+		return e.fail(failureCode(err), fmt.Errorf("download failed: %w", err))
+	}
+
+	if e.json {
+		return e.printJSON(downloadJSON{ID: videoID, Path: path, Warning: warning})
+	}
+	if warning != "" {
+		fmt.Fprintln(e.stderr, "Warning:", warning)
+	}
+	fmt.Fprintln(e.stdout, path)
+	return ExitOK
+}
+
+// playlistJSON is the JSON summary of a playlist download
+type playlistJSON struct {
+	ID      string         `json:"id,omitempty"`
+	Title   string         `json:"title,omitempty"`
+	Success int            `json:"success"`
+	Failed  int            `json:"failed"`
+	Skipped int            `json:"skipped"`
+	Items   []downloadJSON `json:"items"`
+	// Cancelled is set when the download was interrupted; unfinished
+	// items have no path or error
+	Cancelled bool `json:"cancelled,omitempty"`
+	// Failures counts failed items per error category
+	Failures map[youtube.ErrorCategory]int `json:"failures,omitempty"`
+
+	byCategory map[youtube.ErrorCategory][]string
+}
+
+func runPlaylist(e *env, args []string) int {
+	if len(args) != 1 {
+		return e.usageError("playlist: expected exactly one playlist URL")
+	}
+	playlistID := youtube.ExtractPlaylistID(args[0])
+	if playlistID == "" {
+		return e.usageError("playlist: invalid YouTube playlist URL %q", args[0])
+	}
+
+	playlist, err := e.backend.Playlist(e.ctx, playlistID)
+	if err != nil {
+		return e.fail(failureCode(err), fmt.Errorf("failed to fetch playlist: %w", err))
+	}
+	if len(playlist.Items) == 0 {
+		return e.fail(ExitNotFound, fmt.Errorf("no items found in playlist"))
+	}
+	if !e.json {
+		fmt.Fprintf(e.stderr, "Downloading %d songs from %q\n", len(playlist.Items), playlist.Title)
+	}
+
+	job := youtube.PlaylistJob{
+		Name:    playlist.Title,
+		Items:   playlist.Items,
+		Options: e.cfg.DownloadOptions(),
+		Workers: e.cfg.PlaylistWorkers,
+		Skip:    e.skipArchived(),
+	}
+	if e.cfg.AlbumMode {
+		album := youtube.NewAlbum(playlist.Title, playlist.Items)
+		job.Album = &album
+		if !e.json {
+			fmt.Fprintf(e.stderr, "Saving as the album %s\n", album)
+		}
+	}
+	summary := e.downloadItems(job)
+	summary.ID = playlistID
+	summary.Title = playlist.Title
+	return e.finishPlaylist(summary)
+}
+
+func runBatch(e *env, args []string) int {
+	if len(args) != 1 {
+		return e.usageError("batch: expected a file name, or - for stdin")
+	}
+
+	var r io.Reader = e.stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return e.fail(ExitUsage, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	entries, err := youtube.ReadBatch(r)
+	if err != nil {
+		return e.fail(ExitFailure, err)
+	}
+	if len(entries) == 0 {
+		return e.fail(ExitNotFound, fmt.Errorf("batch is empty"))
+	}
+
+	items := youtube.ResolveBatch(e.ctx, e.backend, entries)
+	if !e.json {
+		fmt.Fprintf(e.stderr, "Downloading %d songs from %d batch entries\n", len(items), len(entries))
+	}
+
+	summary := e.downloadItems(youtube.PlaylistJob{
+		Items:   items,
+		Options: e.cfg.DownloadOptions(),
+		Workers: e.cfg.PlaylistWorkers,
+		Skip:    e.skipArchived(),
+	})
+	return e.finishPlaylist(summary)
+}
+
+// downloadItems runs job through the playlist worker pool, printing a line
+// per finished item unless JSON output was requested
+func (e *env) downloadItems(job youtube.PlaylistJob) playlistJSON {
+	summary := playlistJSON{Items: make([]downloadJSON, len(job.Items))}
+	p := youtube.StartPlaylistDownload(e.ctx, e.backend, job)
+
+	for {
+		switch msg := p.Next()().(type) {
+		case youtube.PlaylistDownloadProgressMsg:
+			item := downloadJSON{ID: msg.ID, Title: msg.Title, Path: msg.Path, Skipped: msg.Skipped,
+				Warning: msg.Warning, Error: msg.Error, Category: string(msg.Category)}
+			summary.Items[msg.Index] = item
+			if e.json {
+				continue
+			}
+			if msg.Skipped {
+				fmt.Fprintf(e.stdout, "- [%d/%d] %s: already downloaded\n", msg.Current, msg.Total, msg.Title)
+			} else if msg.Success {
+				fmt.Fprintf(e.stdout, "✓ [%d/%d] %s\n", msg.Current, msg.Total, msg.Path)
+				if msg.Warning != "" {
+					fmt.Fprintf(e.stderr, "  warning: %s\n", msg.Warning)
+				}
+			} else {
+				fmt.Fprintf(e.stdout, "✗ [%d/%d] %s: %s\n", msg.Current, msg.Total, msg.Title, msg.Error)
+			}
+		case youtube.PlaylistDownloadCompleteMsg:
+			summary.Success = msg.Success
+			summary.Failed = msg.Failed
+			summary.Skipped = msg.Skipped
+			summary.byCategory = msg.FailedByCategory
+			summary.Cancelled = msg.Cancelled
+			for category, items := range msg.FailedByCategory {
+				if summary.Failures == nil {
+					summary.Failures = map[youtube.ErrorCategory]int{}
+				}
+				summary.Failures[category] = len(items)
+			}
+			return summary
+		case nil:
+			return summary
+		}
+	}
+}
+
+// finishPlaylist prints the summary and maps the outcome to an exit code
+func (e *env) finishPlaylist(summary playlistJSON) int {
+	if e.json {
+		e.printJSON(summary)
+	} else {
+		status := "Done."
+		if summary.Cancelled {
+			status = "Cancelled."
+		}
+		fmt.Fprintf(e.stderr, "%s Success: %d, Failed: %d, Skipped: %d\n",
+			status, summary.Success, summary.Failed, summary.Skipped)
+		for _, category := range youtube.ErrorCategories {
+			items := summary.byCategory[category]
+			if len(items) == 0 {
+				continue
+			}
+			fmt.Fprintf(e.stderr, "%s (%d):\n", category.Label(), len(items))
+			for _, item := range items {
+				fmt.Fprintf(e.stderr, "  %s\n", item)
+			}
+		}
+	}
+
+	switch {
+	case summary.Cancelled:
+		return ExitCancelled
+	case summary.Failed == 0:
+		return ExitOK
+	case summary.Success == 0 && summary.Skipped == 0:
+		return ExitFailure
+	}
+	return ExitPartial
+}
+
+// progressReporter prints download stage changes to stderr when it is a
+// terminal, keeping stdout clean for scripts
+func (e *env) progressReporter(videoID string) youtube.ProgressFunc {
+	if e.json || !isTerminal(e.stderr) {
+		return nil
+	}
+	var stage string
+	return func(p youtube.Progress) {
+		if p.Stage == stage {
+			return
+		}
+		stage = p.Stage
+		fmt.Fprintf(e.stderr, "%s: %s\n", videoID, stage)
+	}
+}
@@ -0,0 +1,495 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adelapazborrero/music_download/internal/archive"
+	"github.com/adelapazborrero/music_download/internal/coverart"
+	"github.com/adelapazborrero/music_download/internal/lyrics"
+	"github.com/adelapazborrero/music_download/internal/player"
+	"github.com/adelapazborrero/music_download/internal/resume"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// AppName is the directory name used under the XDG base directories
+const AppName = "music-download"
+
+// Config holds user settings. Values are resolved with the precedence
+// flags > environment variables > config file > defaults.
+type Config struct {
+	SearchLimit     int                 `json:"search_limit"`
+	OutputDir       string              `json:"output_dir"`
+	Template        string              `json:"filename_template"`
+	Format          youtube.AudioFormat `json:"audio_format"`
+	Quality         youtube.Quality     `json:"audio_quality"`
+	PlaylistWorkers int                 `json:"playlist_workers"`
+	Retries         int                 `json:"retries"`
+	PlayerCommand   []string            `json:"player_command"`
+	SkimLength      int                 `json:"skim_length"`
+	SkimOffset      int                 `json:"skim_offset"`
+	YTDLPArgs       []string            `json:"ytdlp_args"`
+	ArchiveFile     string              `json:"archive_file"`
+	SkipArchived    bool                `json:"skip_archived"`
+	AlbumMode       bool                `json:"album_mode"`
+	CoverCrop       coverart.Crop       `json:"cover_crop"`
+	CoverSize       int                 `json:"cover_size"`
+	CoverQuality    int                 `json:"cover_quality"`
+	CoverFile       string              `json:"cover_file"`
+	Lyrics          bool                `json:"lyrics"`
+	LyricsLanguage  string              `json:"lyrics_language"`
+	LyricsQuality   int                 `json:"lyrics_min_quality"`
+
+	// sources records where each setting came from, for error messages
+	sources map[string]string
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	return &Config{
+		SearchLimit:     20,
+		OutputDir:       ".",
+		Template:        youtube.DefaultTemplate,
+		Format:          youtube.DefaultFormat,
+		Quality:         youtube.DefaultQuality,
+		PlaylistWorkers: youtube.DefaultPlaylistWorkers,
+		Retries:         youtube.DefaultRetries,
+		PlayerCommand:   []string{"mpv", "--no-video", "--ytdl-format=bestaudio"},
+		SkimLength:      player.DefaultSnippetLength,
+		SkimOffset:      player.DefaultSnippetOffset,
+		SkipArchived:    true,
+		CoverCrop:       coverart.CropSquare,
+		CoverSize:       coverart.DefaultMaxSize,
+		CoverQuality:    coverart.DefaultQuality,
+		LyricsLanguage:  lyrics.DefaultLanguage,
+		LyricsQuality:   lyrics.DefaultMinQuality,
+		sources:         map[string]string{},
+	}
+}
+
+// Dir returns the configuration directory, $XDG_CONFIG_HOME/music-download
+func Dir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, AppName), nil
+}
+
+// DataDir returns the directory for app state such as the download
+// archive, $XDG_DATA_HOME/music-download
+func DataDir() (string, error) {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, AppName), nil
+}
+
+// Path returns the config file location. MUSIC_DOWNLOAD_CONFIG overrides
+// the default of config.json inside Dir.
+func Path() (string, error) {
+	if path := os.Getenv("MUSIC_DOWNLOAD_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Load returns the defaults overridden by the config file (if it exists)
+// and then by environment variables. Flags are applied separately through
+// RegisterFlags.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.loadFile(path); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges the JSON config file at path into cfg
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	// Decode into a map first so we know which keys the file sets
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	for key := range keys {
+		c.sources[key] = "config file " + path
+	}
+	return nil
+}
+
+// languagePattern matches subtitle language codes such as en, fil or pt-BR
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]+)?$`)
+
+// envVars maps environment variables to the setting they override
+var envVars = []struct {
+	name string
+	key  string
+}{
+	{"MUSIC_DOWNLOAD_SEARCH_LIMIT", "search_limit"},
+	{"MUSIC_DOWNLOAD_OUTPUT_DIR", "output_dir"},
+	{"MUSIC_DOWNLOAD_TEMPLATE", "filename_template"},
+	{"MUSIC_DOWNLOAD_FORMAT", "audio_format"},
+	{"MUSIC_DOWNLOAD_QUALITY", "audio_quality"},
+	{"MUSIC_DOWNLOAD_WORKERS", "playlist_workers"},
+	{"MUSIC_DOWNLOAD_RETRIES", "retries"},
+	{"MUSIC_DOWNLOAD_PLAYER", "player_command"},
+	{"MUSIC_DOWNLOAD_SKIM_LENGTH", "skim_length"},
+	{"MUSIC_DOWNLOAD_SKIM_OFFSET", "skim_offset"},
+	{"MUSIC_DOWNLOAD_YTDLP_ARGS", "ytdlp_args"},
+	{"MUSIC_DOWNLOAD_ARCHIVE", "archive_file"},
+	{"MUSIC_DOWNLOAD_SKIP_ARCHIVED", "skip_archived"},
+	{"MUSIC_DOWNLOAD_ALBUM_MODE", "album_mode"},
+	{"MUSIC_DOWNLOAD_COVER_CROP", "cover_crop"},
+	{"MUSIC_DOWNLOAD_COVER_SIZE", "cover_size"},
+	{"MUSIC_DOWNLOAD_COVER_QUALITY", "cover_quality"},
+	{"MUSIC_DOWNLOAD_COVER_FILE", "cover_file"},
+	{"MUSIC_DOWNLOAD_LYRICS", "lyrics"},
+	{"MUSIC_DOWNLOAD_LYRICS_LANGUAGE", "lyrics_language"},
+	{"MUSIC_DOWNLOAD_LYRICS_MIN_QUALITY", "lyrics_min_quality"},
+}
+
+// loadEnv applies MUSIC_DOWNLOAD_* environment variables
+func (c *Config) loadEnv() error {
+	for _, env := range envVars {
+		value, ok := os.LookupEnv(env.name)
+		if !ok {
+			continue
+		}
+		if err := c.set(env.key, value); err != nil {
+			return fmt.Errorf("invalid environment variable %s: %w", env.name, err)
+		}
+		c.sources[env.key] = "environment variable " + env.name
+	}
+	return nil
+}
+
+// set parses value into the setting named key
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "search_limit", "playlist_workers", "retries", "skim_length", "skim_offset", "cover_size", "cover_quality", "lyrics_min_quality":
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		switch key {
+		case "search_limit":
+			c.SearchLimit = n
+		case "playlist_workers":
+			c.PlaylistWorkers = n
+		case "skim_length":
+			c.SkimLength = n
+		case "skim_offset":
+			c.SkimOffset = n
+		case "cover_size":
+			c.CoverSize = n
+		case "cover_quality":
+			c.CoverQuality = n
+		case "lyrics_min_quality":
+			c.LyricsQuality = n
+		default:
+			c.Retries = n
+		}
+	case "output_dir":
+		c.OutputDir = value
+	case "filename_template":
+		c.Template = value
+	case "audio_format":
+		c.Format = youtube.AudioFormat(value)
+	case "audio_quality":
+		c.Quality = youtube.Quality(value)
+	case "player_command":
+		c.PlayerCommand = strings.Fields(value)
+	case "ytdlp_args":
+		c.YTDLPArgs = strings.Fields(value)
+	case "archive_file":
+		c.ArchiveFile = value
+	case "cover_crop":
+		c.CoverCrop = coverart.Crop(value)
+	case "cover_file":
+		c.CoverFile = value
+	case "lyrics_language":
+		c.LyricsLanguage = strings.TrimSpace(value)
+	case "skip_archived", "album_mode", "lyrics":
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		switch key {
+		case "album_mode":
+			c.AlbumMode = b
+		case "lyrics":
+			c.Lyrics = b
+		default:
+			c.SkipArchived = b
+		}
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// flagValue adapts a setting to flag.Value so flags go through set
+type flagValue struct {
+	cfg     *Config
+	name    string
+	key     string
+	get     func() string
+	boolean bool
+}
+
+func (f flagValue) String() string {
+	if f.get == nil {
+		return ""
+	}
+	return f.get()
+}
+
+func (f flagValue) Set(value string) error {
+	if err := f.cfg.set(f.key, value); err != nil {
+		return err
+	}
+	f.cfg.sources[f.key] = "flag --" + f.name
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare --flag
+func (f flagValue) IsBoolFlag() bool {
+	return f.boolean
+}
+
+// RegisterFlags defines command line flags for every setting on fs. Flag
+// defaults show the values loaded from the file and environment.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	define := func(name, key, usage string, get func() string) {
+		fs.Var(flagValue{cfg: c, name: name, key: key, get: get}, name, usage)
+	}
+	define("search-limit", "search_limit", "number of search results per page",
+		func() string { return strconv.Itoa(c.SearchLimit) })
+	define("output-dir", "output_dir", "directory downloads are saved to",
+		func() string { return c.OutputDir })
+	define("template", "filename_template",
+		"filename template; tokens: {artist} {title} {album} {playlist} {index} {date} {id}",
+		func() string { return c.Template })
+	define("format", "audio_format", "audio format: mp3, opus, m4a, flac, vorbis, wav",
+		func() string { return string(c.Format) })
+	define("quality", "audio_quality", "quality preset: v0, v2, 320k, 192k, original",
+		func() string { return string(c.Quality) })
+	define("workers", "playlist_workers", "number of playlist items to download in parallel",
+		func() string { return strconv.Itoa(c.PlaylistWorkers) })
+	define("retries", "retries", "times a download is retried after a network error, 0 to disable",
+		func() string { return strconv.Itoa(c.Retries) })
+	define("player", "player_command", "preview player command, e.g. \"mpv --no-video\"",
+		func() string { return strings.Join(c.PlayerCommand, " ") })
+	define("skim-length", "skim_length", "seconds of each song played in skim mode",
+		func() string { return strconv.Itoa(c.SkimLength) })
+	define("skim-offset", "skim_offset", "where skim mode starts playing, in percent of the song",
+		func() string { return strconv.Itoa(c.SkimOffset) })
+	define("ytdlp-args", "ytdlp_args", "extra arguments passed to every yt-dlp call",
+		func() string { return strings.Join(c.YTDLPArgs, " ") })
+	define("archive", "archive_file", "download archive file (default $XDG_DATA_HOME/music-download/archive.jsonl)",
+		func() string { return c.ArchiveFile })
+	fs.Var(flagValue{cfg: c, name: "skip-archived", key: "skip_archived", boolean: true,
+		get: func() string { return strconv.FormatBool(c.SkipArchived) }},
+		"skip-archived", "skip playlist and batch items that are already in the download archive")
+	fs.Var(flagValue{cfg: c, name: "album", key: "album_mode", boolean: true,
+		get: func() string { return strconv.FormatBool(c.AlbumMode) }},
+		"album", "download playlists as albums: album tags, track numbers, one cover and one folder")
+	define("cover-crop", "cover_crop", "how thumbnails are cropped into cover art: square, trim or none",
+		func() string { return string(c.CoverCrop) })
+	define("cover-size", "cover_size", "maximum cover art width and height in pixels, 0 for no limit",
+		func() string { return strconv.Itoa(c.CoverSize) })
+	define("cover-quality", "cover_quality", "JPEG quality of cover art, 1-100",
+		func() string { return strconv.Itoa(c.CoverQuality) })
+	define("cover-file", "cover_file", "cover image saved next to playlist downloads, e.g. folder.jpg",
+		func() string { return c.CoverFile })
+	fs.Var(flagValue{cfg: c, name: "lyrics", key: "lyrics", boolean: true,
+		get: func() string { return strconv.FormatBool(c.Lyrics) }},
+		"lyrics", "save subtitles as synced lyrics: an .lrc file, embedded in MP3s")
+	define("lyrics-lang", "lyrics_language", "preferred subtitle language for lyrics, e.g. en or pt-BR",
+		func() string { return c.LyricsLanguage })
+	define("lyrics-min-quality", "lyrics_min_quality", "lowest quality of auto-captions used as lyrics, 0-100",
+		func() string { return strconv.Itoa(c.LyricsQuality) })
+}
+
+// Validate checks every setting and normalizes format names and paths
+func (c *Config) Validate() error {
+	if c.SearchLimit < 1 || c.SearchLimit > 500 {
+		return c.invalid("search_limit", fmt.Errorf("must be between 1 and 500, got %d", c.SearchLimit))
+	}
+	if c.PlaylistWorkers < 1 || c.PlaylistWorkers > 32 {
+		return c.invalid("playlist_workers", fmt.Errorf("must be between 1 and 32, got %d", c.PlaylistWorkers))
+	}
+	if c.Retries < 0 || c.Retries > 10 {
+		return c.invalid("retries", fmt.Errorf("must be between 0 and 10, got %d", c.Retries))
+	}
+	if c.SkimLength < 1 || c.SkimLength > 300 {
+		return c.invalid("skim_length", fmt.Errorf("must be between 1 and 300 seconds, got %d", c.SkimLength))
+	}
+	if c.SkimOffset < 0 || c.SkimOffset > 95 {
+		return c.invalid("skim_offset", fmt.Errorf("must be between 0 and 95 percent, got %d", c.SkimOffset))
+	}
+	if strings.TrimSpace(c.OutputDir) == "" {
+		return c.invalid("output_dir", fmt.Errorf("must not be empty"))
+	}
+	if err := youtube.ValidateTemplate(c.Template); err != nil {
+		return c.invalid("filename_template", err)
+	}
+	format, err := youtube.ParseAudioFormat(string(c.Format))
+	if err != nil {
+		return c.invalid("audio_format", err)
+	}
+	quality, err := youtube.ParseQuality(string(c.Quality))
+	if err != nil {
+		return c.invalid("audio_quality", err)
+	}
+	if len(c.PlayerCommand) == 0 {
+		return c.invalid("player_command", fmt.Errorf("must not be empty"))
+	}
+	crop, err := coverart.ParseCrop(string(c.CoverCrop))
+	if err != nil {
+		return c.invalid("cover_crop", err)
+	}
+	if c.CoverSize < 0 || c.CoverSize > 5000 {
+		return c.invalid("cover_size", fmt.Errorf("must be between 0 and 5000 pixels, got %d", c.CoverSize))
+	}
+	if c.CoverQuality < 1 || c.CoverQuality > 100 {
+		return c.invalid("cover_quality", fmt.Errorf("must be between 1 and 100, got %d", c.CoverQuality))
+	}
+	if c.CoverFile != "" {
+		ext := strings.ToLower(filepath.Ext(c.CoverFile))
+		if filepath.Base(c.CoverFile) != c.CoverFile || ext != ".jpg" && ext != ".jpeg" {
+			return c.invalid("cover_file", fmt.Errorf("must be a .jpg file name like folder.jpg, got %q", c.CoverFile))
+		}
+	}
+
+	if !languagePattern.MatchString(c.LyricsLanguage) {
+		return c.invalid("lyrics_language", fmt.Errorf("must be a language code like en or pt-BR, got %q", c.LyricsLanguage))
+	}
+	if c.LyricsQuality < 0 || c.LyricsQuality > 100 {
+		return c.invalid("lyrics_min_quality", fmt.Errorf("must be between 0 and 100, got %d", c.LyricsQuality))
+	}
+
+	c.Format = format
+	c.Quality = quality
+	c.CoverCrop = crop
+	c.OutputDir = expandHome(c.OutputDir)
+	c.ArchiveFile = expandHome(c.ArchiveFile)
+	return nil
+}
+
+// invalid wraps err with the setting name and where its value came from
+func (c *Config) invalid(key string, err error) error {
+	source := c.sources[key]
+	if source == "" {
+		source = "defaults"
+	}
+	return fmt.Errorf("invalid %s (from %s): %w", key, source, err)
+}
+
+// DownloadOptions returns the download settings
+func (c *Config) DownloadOptions() youtube.DownloadOptions {
+	return youtube.DownloadOptions{
+		OutputDir: c.OutputDir,
+		Template:  c.Template,
+		Format:    c.Format,
+		Quality:   c.Quality,
+		CoverArt: coverart.Options{
+			Crop:    c.CoverCrop,
+			MaxSize: c.CoverSize,
+			Quality: c.CoverQuality,
+		},
+		CoverFile: c.CoverFile,
+		Lyrics: lyrics.Options{
+			Enabled:    c.Lyrics,
+			Language:   c.LyricsLanguage,
+			MinQuality: c.LyricsQuality,
+		},
+	}
+}
+
+// Backend returns a yt-dlp backend using the configured extra arguments
+// and retrying network errors
+func (c *Config) Backend() youtube.Backend {
+	b := youtube.NewYTDLPBackend()
+	b.ExtraArgs = c.YTDLPArgs
+	return youtube.WithRetries(b, c.Retries)
+}
+
+// Archive opens the download archive, at ArchiveFile if set
+func (c *Config) Archive() (*archive.Archive, error) {
+	path := c.ArchiveFile
+	if path == "" {
+		dir, err := DataDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "archive.jsonl")
+	}
+	return archive.Open(path)
+}
+
+// Jobs returns the store of unfinished playlist jobs, inside DataDir
+func (c *Config) Jobs() (*resume.Store, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return resume.NewStore(filepath.Join(dir, "jobs")), nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
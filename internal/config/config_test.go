@@ -0,0 +1,189 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adelapazborrero/music_download/internal/coverart"
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// loadWith loads the config from a file with the given JSON, then applies
+// env and the flags in args
+func loadWith(t *testing.T, file string, env map[string]string, args ...string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if file != "" {
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("MUSIC_DOWNLOAD_CONFIG", path)
+	// Settings of the environment running the tests don't count
+	for _, v := range envVars {
+		t.Setenv(v.name, "")
+		os.Unsetenv(v.name)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func TestPrecedence(t *testing.T) {
+	file := `{"search_limit": 5, "output_dir": "/file", "audio_format": "flac", "playlist_workers": 2}`
+	env := map[string]string{
+		"MUSIC_DOWNLOAD_SEARCH_LIMIT": "10",
+		"MUSIC_DOWNLOAD_OUTPUT_DIR":   "/env",
+	}
+
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want func(*Config) bool
+	}{
+		{
+			name: "defaults",
+			want: func(c *Config) bool {
+				return c.SearchLimit == 20 && c.OutputDir == "." && c.Format == youtube.DefaultFormat
+			},
+		},
+		{
+			name: "file over defaults",
+			file: file,
+			want: func(c *Config) bool {
+				return c.SearchLimit == 5 && c.OutputDir == "/file" && c.Format == "flac" && c.Retries == youtube.DefaultRetries
+			},
+		},
+		{
+			name: "env over file",
+			file: file,
+			env:  env,
+			want: func(c *Config) bool {
+				return c.SearchLimit == 10 && c.OutputDir == "/env" && c.Format == "flac"
+			},
+		},
+		{
+			name: "flags over env",
+			file: file,
+			env:  env,
+			args: []string{"--search-limit", "15", "--workers=3", "--album"},
+			want: func(c *Config) bool {
+				return c.SearchLimit == 15 && c.OutputDir == "/env" && c.PlaylistWorkers == 3 && c.AlbumMode
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.file, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(cfg) {
+				t.Errorf("got %+v", *cfg)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown key", `{"search_limt": 5}`, nil, "search_limt"},
+		{"bad json", `{`, nil, "parsing config file"},
+		{"bad number", "", map[string]string{"MUSIC_DOWNLOAD_WORKERS": "four"}, "MUSIC_DOWNLOAD_WORKERS"},
+		{"bad bool", "", map[string]string{"MUSIC_DOWNLOAD_LYRICS": "sometimes"}, "MUSIC_DOWNLOAD_LYRICS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWith(t, tt.file, tt.env)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		// wantErr is part of the error, "" for a valid config
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "search limit", args: []string{"--search-limit", "0"}, wantErr: "invalid search_limit (from flag --search-limit)"},
+		{name: "workers", env: map[string]string{"MUSIC_DOWNLOAD_WORKERS": "64"},
+			wantErr: "invalid playlist_workers (from environment variable MUSIC_DOWNLOAD_WORKERS)"},
+		{name: "retries", file: `{"retries": 11}`, wantErr: "invalid retries (from config file"},
+		{name: "skim offset", args: []string{"--skim-offset", "96"}, wantErr: "invalid skim_offset"},
+		{name: "empty output dir", args: []string{"--output-dir", " "}, wantErr: "invalid output_dir"},
+		{name: "template", args: []string{"--template", "{nope}"}, wantErr: "invalid filename_template"},
+		{name: "format", args: []string{"--format", "mp4"}, wantErr: "invalid audio_format"},
+		{name: "quality", args: []string{"--quality", "high"}, wantErr: "invalid audio_quality"},
+		{name: "player", args: []string{"--player", ""}, wantErr: "invalid player_command"},
+		{name: "cover crop", args: []string{"--cover-crop", "circle"}, wantErr: "invalid cover_crop"},
+		{name: "cover size", args: []string{"--cover-size", "-1"}, wantErr: "invalid cover_size"},
+		{name: "cover quality", args: []string{"--cover-quality", "0"}, wantErr: "invalid cover_quality"},
+		{name: "cover file", args: []string{"--cover-file", "folder.png"}, wantErr: "invalid cover_file"},
+		{name: "cover file path", args: []string{"--cover-file", "art/folder.jpg"}, wantErr: "invalid cover_file"},
+		{name: "lyrics language", args: []string{"--lyrics-lang", "english"}, wantErr: "invalid lyrics_language"},
+		{name: "lyrics quality", args: []string{"--lyrics-min-quality", "101"}, wantErr: "invalid lyrics_min_quality"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.file, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cfg, err := loadWith(t, "", nil, "--format", "ogg", "--quality", "320K", "--cover-crop", "Trim", "--output-dir", "~/Music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Format != youtube.FormatVorbis || cfg.Quality != youtube.QualityCBR320 || cfg.CoverCrop != coverart.CropTrim {
+		t.Errorf("got format %q, quality %q, crop %q", cfg.Format, cfg.Quality, cfg.CoverCrop)
+	}
+	if want := filepath.Join(home, "Music"); cfg.OutputDir != want {
+		t.Errorf("OutputDir = %q, want %q", cfg.OutputDir, want)
+	}
+}
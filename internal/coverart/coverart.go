@@ -0,0 +1,188 @@
+// Package coverart turns video thumbnails into album cover art: cropped to
+// a square or trimmed of letterbox bars, limited in size and saved as JPEG.
+package coverart
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"strings"
+)
+
+// Crop selects how a thumbnail is cropped
+type Crop string
+
+const (
+	// CropSquare cuts the largest centered square out of the thumbnail
+	CropSquare Crop = "square"
+	// CropTrim removes black letterbox and pillarbox bars only
+	CropTrim Crop = "trim"
+	// CropNone keeps the whole thumbnail
+	CropNone Crop = "none"
+)
+
+// Crops lists the crop modes in the order they are documented
+var Crops = []Crop{CropSquare, CropTrim, CropNone}
+
+const (
+	// DefaultMaxSize is the default limit on the cover's width and height
+	DefaultMaxSize = 1000
+	// DefaultQuality is the default JPEG quality
+	DefaultQuality = 90
+)
+
+// Options controls how thumbnails are processed. The zero value crops to a
+// square at the default quality without limiting the size.
+type Options struct {
+	Crop Crop `json:"crop,omitempty"`
+	// MaxSize limits the width and height in pixels; 0 keeps the size
+	MaxSize int `json:"max_size,omitempty"`
+	// Quality is the JPEG quality, 1-100
+	Quality int `json:"quality,omitempty"`
+}
+
+// ParseCrop parses a crop mode name
+func ParseCrop(s string) (Crop, error) {
+	for _, c := range Crops {
+		if strings.EqualFold(s, string(c)) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown cover crop %q (valid: square, trim, none)", s)
+}
+
+// Process reads the image at src, crops and scales it as opts says and
+// writes it to dst as JPEG
+func Process(src, dst string, opts Options) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", src, err)
+	}
+
+	switch opts.Crop {
+	case CropTrim:
+		img = crop(img, trimBars(img))
+	case CropNone:
+	default:
+		img = crop(img, centerSquare(img.Bounds()))
+	}
+	if opts.MaxSize > 0 {
+		img = fit(img, opts.MaxSize)
+	}
+
+	quality := opts.Quality
+	if quality < 1 || quality > 100 {
+		quality = DefaultQuality
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: quality}); err != nil {
+		out.Close()
+		return fmt.Errorf("encoding %s: %w", dst, err)
+	}
+	return out.Close()
+}
+
+// centerSquare returns the largest square centered in r
+func centerSquare(r image.Rectangle) image.Rectangle {
+	size := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-size)/2
+	y := r.Min.Y + (r.Dy()-size)/2
+	return image.Rect(x, y, x+size, y+size)
+}
+
+// trimBars returns the bounds of img without the dark bars along its
+// edges. At least half of each dimension is kept so a dark picture isn't
+// cropped away.
+func trimBars(img image.Image) image.Rectangle {
+	r := img.Bounds()
+	isBar := func(x0, y0, x1, y1 int) bool {
+		dark, total := 0, 0
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				cr, cg, cb, _ := img.At(x, y).RGBA()
+				// JPEG noise keeps bars from being pure black
+				if cr>>8 <= 24 && cg>>8 <= 24 && cb>>8 <= 24 {
+					dark++
+				}
+				total++
+			}
+		}
+		return dark*100 >= total*98
+	}
+
+	t := r
+	for t.Min.Y < t.Max.Y && t.Dy() > r.Dy()/2 && isBar(t.Min.X, t.Min.Y, t.Max.X, t.Min.Y+1) {
+		t.Min.Y++
+	}
+	for t.Min.Y < t.Max.Y && t.Dy() > r.Dy()/2 && isBar(t.Min.X, t.Max.Y-1, t.Max.X, t.Max.Y) {
+		t.Max.Y--
+	}
+	for t.Min.X < t.Max.X && t.Dx() > r.Dx()/2 && isBar(t.Min.X, t.Min.Y, t.Min.X+1, t.Max.Y) {
+		t.Min.X++
+	}
+	for t.Min.X < t.Max.X && t.Dx() > r.Dx()/2 && isBar(t.Max.X-1, t.Min.Y, t.Max.X, t.Max.Y) {
+		t.Max.X--
+	}
+	return t
+}
+
+// crop returns the part of img inside r
+func crop(img image.Image, r image.Rectangle) image.Image {
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	return out
+}
+
+// fit scales img down so neither side exceeds size, averaging the source
+// pixels each output pixel covers. Smaller images are returned unchanged.
+func fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = crop(img, b).(*image.RGBA)
+		b = src.Bounds()
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := out.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				out.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package coverart
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// letterboxed returns a w×h black image with a white picture inside pic
+func letterboxed(w, h int, pic image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{A: 255}
+			if (image.Point{x, y}).In(pic) {
+				c = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestParseCrop(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Crop
+		wantErr bool
+	}{
+		{"square", CropSquare, false},
+		{"Trim", CropTrim, false},
+		{"NONE", CropNone, false},
+		{"circle", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCrop(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseCrop(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCenterSquare(t *testing.T) {
+	tests := []struct {
+		r, want image.Rectangle
+	}{
+		{image.Rect(0, 0, 160, 90), image.Rect(35, 0, 125, 90)},
+		{image.Rect(0, 0, 90, 160), image.Rect(0, 35, 90, 125)},
+		{image.Rect(10, 10, 20, 20), image.Rect(10, 10, 20, 20)},
+	}
+	for _, tt := range tests {
+		if got := centerSquare(tt.r); got != tt.want {
+			t.Errorf("centerSquare(%v) = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestTrimBars(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want image.Rectangle
+	}{
+		{
+			name: "letterbox",
+			img:  letterboxed(160, 120, image.Rect(0, 15, 160, 105)),
+			want: image.Rect(0, 15, 160, 105),
+		},
+		{
+			name: "pillarbox",
+			img:  letterboxed(160, 90, image.Rect(35, 0, 125, 90)),
+			want: image.Rect(35, 0, 125, 90),
+		},
+		{
+			name: "no bars",
+			img:  letterboxed(40, 30, image.Rect(0, 0, 40, 30)),
+			want: image.Rect(0, 0, 40, 30),
+		},
+		{
+			name: "dark picture keeps half of each side",
+			img:  letterboxed(40, 20, image.Rectangle{}),
+			want: image.Rect(20, 10, 40, 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimBars(tt.img); got != tt.want {
+				t.Errorf("trimBars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{1280, 720, 500, 500, 281},
+		{720, 1280, 500, 281, 500},
+		{300, 200, 500, 300, 200},
+		{2000, 1, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		got := fit(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.size).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("fit(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+
+	// Each output pixel averages the pixels it covers
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		v := uint8(0)
+		if x%2 == 1 {
+			v = 200
+		}
+		img.SetRGBA(x, 0, color.RGBA{v, v, v, 255})
+		img.SetRGBA(x, 1, color.RGBA{v, v, v, 255})
+	}
+	if got := fit(img, 2).(*image.RGBA).RGBAAt(0, 0); got != (color.RGBA{100, 100, 100, 255}) {
+		t.Errorf("fit() averaged pixel = %v, want gray 100", got)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "thumbnail.jpg")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(f, letterboxed(640, 480, image.Rect(0, 60, 640, 420)), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name         string
+		opts         Options
+		wantW, wantH int
+	}{
+		{"square by default", Options{}, 480, 480},
+		{"square limited in size", Options{Crop: CropSquare, MaxSize: 100}, 100, 100},
+		{"trim", Options{Crop: CropTrim}, 640, 360},
+		{"none", Options{Crop: CropNone, Quality: 50}, 640, 480},
+		{"bad quality falls back", Options{Crop: CropNone, Quality: 500, MaxSize: 320}, 320, 240},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(dir, "cover.jpg")
+			if err := Process(src, dst, tt.opts); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			out, err := os.Open(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			cfg, err := jpeg.DecodeConfig(out)
+			if err != nil {
+				t.Fatalf("Process() wrote no JPEG: %v", err)
+			}
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("Process() = %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+
+	if err := Process(filepath.Join(dir, "missing.jpg"), filepath.Join(dir, "out.jpg"), Options{}); err == nil {
+		t.Error("Process() of a missing file succeeded")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.jpg"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Process(filepath.Join(dir, "bad.jpg"), filepath.Join(dir, "out.jpg"), Options{}); err == nil {
+		t.Error("Process() of a broken image succeeded")
+	}
+}
@@ -0,0 +1,331 @@
+package coverart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pictureKey is the Vorbis comment holding embedded pictures
+const pictureKey = "METADATA_BLOCK_PICTURE"
+
+// oggPage is a page of an Ogg bitstream
+type oggPage struct {
+	flags   byte
+	granule uint64
+	serial  uint32
+	// lacing holds the segment sizes; a size below 255 ends a packet
+	lacing []byte
+	data   []byte
+}
+
+const (
+	oggContinued = 0x01
+	oggFirst     = 0x02
+)
+
+// oggCodec describes the header packets of a codec carried in Ogg
+type oggCodec struct {
+	// headers is the number of header packets, the comment one second
+	headers int
+	// commentPrefix starts the comment packet
+	commentPrefix string
+}
+
+var (
+	opusCodec   = oggCodec{headers: 2, commentPrefix: "OpusTags"}
+	vorbisCodec = oggCodec{headers: 3, commentPrefix: "\x03vorbis"}
+)
+
+// EmbedOgg makes the image at cover the front cover of the Ogg Opus or
+// Vorbis file at path, as the METADATA_BLOCK_PICTURE comment players read.
+// Pictures already in the file are replaced; the audio is left as it is.
+func EmbedOgg(path, cover string) error {
+	picture, err := pictureBlock(cover)
+	if err != nil {
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	out, err := embedOggPicture(data, picture)
+	if err != nil {
+		return fmt.Errorf("embedding cover in %s: %w", filepath.Base(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cover-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// pictureBlock returns the image at path as a FLAC picture block, the value
+// of METADATA_BLOCK_PICTURE before base64 encoding
+func pictureBlock(path string) ([]byte, error) {
+	img, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	mime := "image/" + format
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, 3) // front cover
+	b = binary.BigEndian.AppendUint32(b, uint32(len(mime)))
+	b = append(b, mime...)
+	b = binary.BigEndian.AppendUint32(b, 0) // no description
+	b = binary.BigEndian.AppendUint32(b, uint32(cfg.Width))
+	b = binary.BigEndian.AppendUint32(b, uint32(cfg.Height))
+	b = binary.BigEndian.AppendUint32(b, 24) // bits per pixel
+	b = binary.BigEndian.AppendUint32(b, 0)  // not indexed
+	b = binary.BigEndian.AppendUint32(b, uint32(len(img)))
+	return append(b, img...), nil
+}
+
+// embedOggPicture returns the Ogg file data with picture as its only
+// embedded picture. The header pages are rebuilt around the new comment
+// packet and the audio pages renumbered after them.
+func embedOggPicture(data, picture []byte) ([]byte, error) {
+	pages, err := readOggPages(data)
+	if err != nil {
+		return nil, err
+	}
+	serial := pages[0].serial
+	for _, p := range pages {
+		if p.serial != serial {
+			return nil, fmt.Errorf("files with several Ogg streams aren't supported")
+		}
+	}
+
+	var codec oggCodec
+	switch first := pages[0].data; {
+	case bytes.HasPrefix(first, []byte("OpusHead")):
+		codec = opusCodec
+	case bytes.HasPrefix(first, []byte("\x01vorbis")):
+		codec = vorbisCodec
+	default:
+		return nil, fmt.Errorf("not an Opus or Vorbis file")
+	}
+
+	// The headers end on a page of their own, before the audio
+	var packets [][]byte
+	var packet []byte
+	audio := -1
+	for i := 0; i < len(pages) && audio < 0; i++ {
+		offset := 0
+		for j, size := range pages[i].lacing {
+			packet = append(packet, pages[i].data[offset:offset+int(size)]...)
+			offset += int(size)
+			if size == 255 {
+				continue
+			}
+			packets, packet = append(packets, packet), nil
+			if len(packets) == codec.headers {
+				if j != len(pages[i].lacing)-1 {
+					return nil, fmt.Errorf("audio shares a page with the headers")
+				}
+				audio = i + 1
+				break
+			}
+		}
+	}
+	if audio < 0 {
+		return nil, fmt.Errorf("truncated Ogg headers")
+	}
+
+	comment, err := replacePicture(packets[1], codec, picture)
+	if err != nil {
+		return nil, err
+	}
+	packets[1] = comment
+
+	out := oggPaginate(serial, oggFirst, packets[:1])
+	out = append(out, oggPaginate(serial, 0, packets[1:])...)
+	out = append(out, pages[audio:]...)
+
+	var b bytes.Buffer
+	for i, p := range out {
+		b.Write(p.encode(uint32(i)))
+	}
+	return b.Bytes(), nil
+}
+
+// replacePicture returns the comment packet with every picture comment
+// replaced by picture
+func replacePicture(packet []byte, codec oggCodec, picture []byte) ([]byte, error) {
+	errMalformed := fmt.Errorf("malformed comment header")
+	rest, ok := bytes.CutPrefix(packet, []byte(codec.commentPrefix))
+	if !ok {
+		return nil, errMalformed
+	}
+	field := func() ([]byte, bool) {
+		if len(rest) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(rest)
+		if uint64(n) > uint64(len(rest)-4) {
+			return nil, false
+		}
+		f := rest[4 : 4+n]
+		rest = rest[4+n:]
+		return f, true
+	}
+
+	vendor, ok := field()
+	if !ok || len(rest) < 4 {
+		return nil, errMalformed
+	}
+	count := binary.LittleEndian.Uint32(rest)
+	rest = rest[4:]
+	var comments [][]byte
+	for range count {
+		c, ok := field()
+		if !ok {
+			return nil, errMalformed
+		}
+		key, _, _ := strings.Cut(string(c), "=")
+		if !strings.EqualFold(key, pictureKey) {
+			comments = append(comments, c)
+		}
+	}
+	comments = append(comments, []byte(pictureKey+"="+base64.StdEncoding.EncodeToString(picture)))
+
+	out := []byte(codec.commentPrefix)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(vendor)))
+	out = append(out, vendor...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(comments)))
+	for _, c := range comments {
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(c)))
+		out = append(out, c...)
+	}
+	// Vorbis' framing bit or Opus' padding
+	return append(out, rest...), nil
+}
+
+// readOggPages splits an Ogg bitstream into its pages
+func readOggPages(data []byte) ([]oggPage, error) {
+	var pages []oggPage
+	for len(data) > 0 {
+		if len(data) < 27 || string(data[:4]) != "OggS" || data[4] != 0 {
+			return nil, fmt.Errorf("not an Ogg file")
+		}
+		n := int(data[26])
+		if len(data) < 27+n {
+			return nil, fmt.Errorf("truncated Ogg page")
+		}
+		p := oggPage{
+			flags:   data[5],
+			granule: binary.LittleEndian.Uint64(data[6:14]),
+			serial:  binary.LittleEndian.Uint32(data[14:18]),
+			lacing:  data[27 : 27+n],
+		}
+		size := 0
+		for _, s := range p.lacing {
+			size += int(s)
+		}
+		if len(data) < 27+n+size {
+			return nil, fmt.Errorf("truncated Ogg page")
+		}
+		p.data = data[27+n : 27+n+size]
+		pages = append(pages, p)
+		data = data[27+n+size:]
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("not an Ogg file")
+	}
+	return pages, nil
+}
+
+// oggPaginate lays packets out on as few header pages as fit them. The
+// first page gets flags; header pages have granule position 0.
+func oggPaginate(serial uint32, flags byte, packets [][]byte) []oggPage {
+	var lacing, data []byte
+	for _, packet := range packets {
+		for n := len(packet); ; n -= 255 {
+			lacing = append(lacing, byte(min(n, 255)))
+			if n < 255 {
+				break
+			}
+		}
+		data = append(data, packet...)
+	}
+
+	var pages []oggPage
+	for len(lacing) > 0 {
+		n := min(len(lacing), 255)
+		size := 0
+		for _, s := range lacing[:n] {
+			size += int(s)
+		}
+		pages = append(pages, oggPage{flags: flags, serial: serial, lacing: lacing[:n], data: data[:size]})
+		// The next page continues a packet if this one ends mid-packet
+		flags = 0
+		if lacing[n-1] == 255 {
+			flags = oggContinued
+		}
+		lacing, data = lacing[n:], data[size:]
+	}
+	return pages
+}
+
+// encode returns the page as sequence number seq of its stream
+func (p oggPage) encode(seq uint32) []byte {
+	b := []byte("OggS\x00")
+	b = append(b, p.flags)
+	b = binary.LittleEndian.AppendUint64(b, p.granule)
+	b = binary.LittleEndian.AppendUint32(b, p.serial)
+	b = binary.LittleEndian.AppendUint32(b, seq)
+	b = append(b, 0, 0, 0, 0, byte(len(p.lacing)))
+	b = append(b, p.lacing...)
+	b = append(b, p.data...)
+	binary.LittleEndian.PutUint32(b[22:26], oggCRC(b))
+	return b
+}
+
+// oggCRCTable is the lookup table of Ogg's CRC-32: polynomial 0x04c11db7,
+// not reflected
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for range 8 {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+// oggCRC returns the checksum of a page whose checksum field is zero
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
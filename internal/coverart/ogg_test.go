@@ -0,0 +1,257 @@
+package coverart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commentPacket builds a comment header packet
+func commentPacket(prefix, trailer string, comments ...string) []byte {
+	b := []byte(prefix)
+	b = binary.LittleEndian.AppendUint32(b, 4)
+	b = append(b, "test"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return append(b, trailer...)
+}
+
+// oggFile lays headers out the way encoders do and appends two audio pages
+func oggFile(headers [][]byte) (data []byte, audio [][]byte) {
+	pages := oggPaginate(7, oggFirst, headers[:1])
+	pages = append(pages, oggPaginate(7, 0, headers[1:])...)
+	audio = [][]byte{bytes.Repeat([]byte{1}, 300), bytes.Repeat([]byte{2}, 40)}
+	pages = append(pages,
+		oggPage{granule: 960, serial: 7, lacing: []byte{255, 45}, data: audio[0]},
+		oggPage{flags: 0x04, granule: 1920, serial: 7, lacing: []byte{40}, data: audio[1]},
+	)
+	for i, p := range pages {
+		data = append(data, p.encode(uint32(i))...)
+	}
+	return data, audio
+}
+
+// oggPackets reassembles the packets of pages
+func oggPackets(pages []oggPage) [][]byte {
+	var packets [][]byte
+	var packet []byte
+	for _, p := range pages {
+		offset := 0
+		for _, size := range p.lacing {
+			packet = append(packet, p.data[offset:offset+int(size)]...)
+			offset += int(size)
+			if size < 255 {
+				packets, packet = append(packets, packet), nil
+			}
+		}
+	}
+	return packets
+}
+
+// parseComments returns the comments of a comment packet
+func parseComments(t *testing.T, packet []byte, prefix string) []string {
+	t.Helper()
+	rest := packet[len(prefix):]
+	rest = rest[4+binary.LittleEndian.Uint32(rest):]
+	count := binary.LittleEndian.Uint32(rest)
+	rest = rest[4:]
+	var comments []string
+	for range count {
+		n := binary.LittleEndian.Uint32(rest)
+		comments = append(comments, string(rest[4:4+n]))
+		rest = rest[4+n:]
+	}
+	return comments
+}
+
+func TestEmbedOgg(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.jpg")
+	f, err := os.Create(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(f, letterboxed(32, 24, image.Rect(0, 0, 32, 24)), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	img, _ := os.ReadFile(cover)
+
+	oldPicture := "METADATA_BLOCK_PICTURE=" + base64.StdEncoding.EncodeToString([]byte("old"))
+	tests := []struct {
+		name, ext, prefix, trailer string
+		headers                    func(comment []byte) [][]byte
+	}{
+		{
+			name: "opus", ext: ".opus", prefix: "OpusTags",
+			headers: func(comment []byte) [][]byte {
+				return [][]byte{append([]byte("OpusHead"), make([]byte, 11)...), comment}
+			},
+		},
+		{
+			name: "vorbis", ext: ".ogg", prefix: "\x03vorbis", trailer: "\x01",
+			headers: func(comment []byte) [][]byte {
+				return [][]byte{
+					append([]byte("\x01vorbis"), make([]byte, 23)...),
+					comment,
+					append([]byte("\x05vorbis"), bytes.Repeat([]byte{9}, 600)...),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := tt.headers(commentPacket(tt.prefix, tt.trailer, "TITLE=Song", oldPicture))
+			data, audio := oggFile(headers)
+			path := filepath.Join(dir, "song"+tt.ext)
+			if err := os.WriteFile(path, data, 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := EmbedOgg(path, cover); err != nil {
+				t.Fatalf("EmbedOgg: %v", err)
+			}
+			out, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+				t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+			}
+
+			pages, err := readOggPages(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var encoded []byte
+			for i, p := range pages {
+				encoded = append(encoded, p.encode(uint32(i))...)
+			}
+			if !bytes.Equal(encoded, out) {
+				t.Error("page sequence numbers or checksums are off")
+			}
+			if pages[0].flags != oggFirst || len(pages[0].lacing) != 1 {
+				t.Errorf("first page has flags %#x and %d segments, want the first header alone", pages[0].flags, len(pages[0].lacing))
+			}
+
+			packets := oggPackets(pages)
+			if len(packets) != len(headers)+len(audio) {
+				t.Fatalf("got %d packets, want %d", len(packets), len(headers)+len(audio))
+			}
+			for i, want := range append(headers[:1:1], headers[2:]...) {
+				got := packets[i]
+				if i > 0 {
+					got = packets[i+1]
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("header %d changed", i)
+				}
+			}
+			for i, want := range audio {
+				if !bytes.Equal(packets[len(headers)+i], want) {
+					t.Errorf("audio packet %d changed", i)
+				}
+			}
+			if !bytes.HasSuffix(packets[1], []byte(tt.trailer)) {
+				t.Error("comment header lost its trailer")
+			}
+
+			comments := parseComments(t, packets[1], tt.prefix)
+			if len(comments) != 2 || comments[0] != "TITLE=Song" {
+				t.Fatalf("comments = %.40q, want the title and one picture", comments)
+			}
+			value, ok := strings.CutPrefix(comments[1], "METADATA_BLOCK_PICTURE=")
+			if !ok {
+				t.Fatalf("second comment is %.40q, want the picture", comments[1])
+			}
+			block, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := pictureBlock(cover)
+			if !bytes.Equal(block, want) {
+				t.Error("picture block differs")
+			}
+			u32 := func(off int) uint32 { return binary.BigEndian.Uint32(block[off:]) }
+			if u32(0) != 3 || string(block[8:18]) != "image/jpeg" || u32(22) != 32 || u32(26) != 24 {
+				t.Errorf("picture header = %v, want a 32×24 JPEG front cover", block[:34])
+			}
+			if !bytes.Equal(block[42:], img) || int(u32(38)) != len(img) {
+				t.Error("picture data differs from the cover")
+			}
+		})
+	}
+}
+
+func TestEmbedOggErrors(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.jpg")
+	f, _ := os.Create(cover)
+	jpeg.Encode(f, letterboxed(8, 8, image.Rect(0, 0, 8, 8)), nil)
+	f.Close()
+
+	flac, _ := oggFile([][]byte{[]byte("\x7fFLAC"), []byte("x")})
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not ogg", []byte("ID3\x04\x00")},
+		{"other codec", flac},
+		{"truncated", flac[:20]},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".ogg")
+		os.WriteFile(path, tt.data, 0o644)
+		if err := EmbedOgg(path, cover); err == nil {
+			t.Errorf("%s: EmbedOgg succeeded", tt.name)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: file was changed", tt.name)
+		}
+	}
+}
+
+func TestOggPaginate(t *testing.T) {
+	packets := [][]byte{
+		bytes.Repeat([]byte{1}, 510),
+		bytes.Repeat([]byte{2}, 70000),
+		{3},
+	}
+	pages := oggPaginate(1, 0, packets)
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(pages))
+	}
+	if pages[0].flags != 0 || pages[1].flags != oggContinued {
+		t.Errorf("flags = %#x, %#x; want the second page to continue a packet", pages[0].flags, pages[1].flags)
+	}
+	for i, p := range pages {
+		if len(p.lacing) > 255 || p.granule != 0 {
+			t.Errorf("page %d has %d segments and granule %d", i, len(p.lacing), p.granule)
+		}
+	}
+	got := oggPackets(pages)
+	if len(got) != len(packets) {
+		t.Fatalf("got %d packets back, want %d", len(got), len(packets))
+	}
+	for i := range packets {
+		if !bytes.Equal(got[i], packets[i]) {
+			t.Errorf("packet %d changed", i)
+		}
+	}
+}
+
+func TestOggCRC(t *testing.T) {
+	// Ogg's CRC-32 is CRC-32/MPEG-2 without the initial value and final xor
+	if got := oggCRC([]byte("123456789")); got != 0x89a1897f {
+		t.Errorf("oggCRC = %#x, want 0x89a1897f", got)
+	}
+}
@@ -0,0 +1,175 @@
+package lyrics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3Padding is left free in a rewritten tag so later edits fit in place
+const id3Padding = 1024
+
+// id3Languages maps the ISO 639-1 codes of common subtitle languages to the
+// ISO 639-2 codes lyrics frames use
+var id3Languages = map[string]string{
+	"ar": "ara", "de": "deu", "en": "eng", "es": "spa", "fr": "fra",
+	"hi": "hin", "id": "ind", "it": "ita", "ja": "jpn", "ko": "kor",
+	"nl": "nld", "pl": "pol", "pt": "por", "ru": "rus", "sv": "swe",
+	"tr": "tur", "uk": "ukr", "vi": "vie", "zh": "zho",
+}
+
+// Embed writes l into the ID3v2 tag of the MP3 file at path as an
+// unsynchronized (USLT) and a synchronized (SYLT) lyrics frame, replacing
+// any lyrics frames already there. Files without a tag get an ID3v2.3 one.
+func Embed(path string, l *Lyrics) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	version := byte(3)
+	var frames []byte
+	audio := data
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		version = data[3]
+		flags := data[5]
+		// Unsynchronised tags and extended headers aren't worth supporting;
+		// ffmpeg and yt-dlp write neither
+		if version < 3 || version > 4 || flags&0xc0 != 0 {
+			return fmt.Errorf("embedding lyrics: unsupported ID3v2.%d tag", version)
+		}
+		size := syncsafe(data[6:10])
+		end := 10 + size
+		if flags&0x10 != 0 {
+			end += 10 // footer
+		}
+		if end > len(data) {
+			return fmt.Errorf("embedding lyrics: truncated ID3 tag")
+		}
+		frames = otherFrames(data[10:10+size], version)
+		audio = data[end:]
+	}
+
+	lang := id3Language(l.Language)
+	frames = append(frames, id3Frame("USLT", version, usltBody(l, lang))...)
+	frames = append(frames, id3Frame("SYLT", version, syltBody(l, lang))...)
+
+	var out bytes.Buffer
+	out.WriteString("ID3")
+	out.Write([]byte{version, 0, 0})
+	out.Write(putSyncsafe(len(frames) + id3Padding))
+	out.Write(frames)
+	out.Write(make([]byte, id3Padding))
+	out.Write(audio)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lyrics-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("embedding lyrics: %w", err)
+	}
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("embedding lyrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("embedding lyrics: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// otherFrames returns the frames of a tag body except lyrics frames,
+// dropping the padding
+func otherFrames(body []byte, version byte) []byte {
+	var kept []byte
+	for len(body) >= 10 && body[0] != 0 {
+		size := int(binary.BigEndian.Uint32(body[4:8]))
+		if version == 4 {
+			size = syncsafe(body[4:8])
+		}
+		if 10+size > len(body) {
+			break
+		}
+		if id := string(body[:4]); id != "USLT" && id != "SYLT" {
+			kept = append(kept, body[:10+size]...)
+		}
+		body = body[10+size:]
+	}
+	return kept
+}
+
+// id3Frame builds a frame with no flags set
+func id3Frame(id string, version byte, body []byte) []byte {
+	frame := []byte(id)
+	if version == 4 {
+		frame = append(frame, putSyncsafe(len(body))...)
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	}
+	frame = append(frame, 0, 0)
+	return append(frame, body...)
+}
+
+// usltBody is the body of an unsynchronized lyrics frame: encoding,
+// language, an empty description and the text
+func usltBody(l *Lyrics, lang string) []byte {
+	body := append([]byte{1}, lang...)
+	body = append(body, utf16Text("", true)...)
+	return append(body, utf16Text(l.Text(), false)...)
+}
+
+// syltBody is the body of a synchronized lyrics frame: encoding, language,
+// millisecond timestamps, the lyrics content type, an empty description,
+// then each line followed by its time
+func syltBody(l *Lyrics, lang string) []byte {
+	body := append([]byte{1}, lang...)
+	body = append(body, 2, 1)
+	body = append(body, utf16Text("", true)...)
+	for _, line := range l.Lines {
+		body = append(body, utf16Text(line.Text, true)...)
+		body = binary.BigEndian.AppendUint32(body, uint32(line.Time.Milliseconds()))
+	}
+	return body
+}
+
+// utf16Text encodes s as UTF-16 with a byte order mark, the one Unicode
+// encoding both ID3v2.3 and v2.4 read, optionally NUL-terminated
+func utf16Text(s string, terminated bool) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	if terminated {
+		b = append(b, 0, 0)
+	}
+	return b
+}
+
+// id3Language returns the three-letter code of a subtitle language such as
+// "en" or "en-US", "XXX" when it is unknown
+func id3Language(lang string) string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	if len(base) == 3 {
+		return base
+	}
+	if code, ok := id3Languages[base]; ok {
+		return code
+	}
+	return "XXX"
+}
+
+// syncsafe decodes a 28-bit integer stored in 4 bytes of 7 bits
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+func putSyncsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
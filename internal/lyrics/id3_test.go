@@ -0,0 +1,166 @@
+package lyrics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// audio stands in for the MPEG frames after the tag
+var audio = []byte{0xff, 0xfb, 0x90, 0x64, 1, 2, 3, 4}
+
+// readTag splits an ID3v2 file into its frames by ID and the audio after
+// the tag
+func readTag(t *testing.T, data []byte) (version byte, frames map[string][]byte, rest []byte) {
+	t.Helper()
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		t.Fatalf("no ID3 tag in % x", data[:min(len(data), 10)])
+	}
+	version = data[3]
+	size := syncsafe(data[6:10])
+	body := data[10 : 10+size]
+	frames = map[string][]byte{}
+	for len(body) >= 10 && body[0] != 0 {
+		n := int(binary.BigEndian.Uint32(body[4:8]))
+		if version == 4 {
+			n = syncsafe(body[4:8])
+		}
+		frames[string(body[:4])] = body[10 : 10+n]
+		body = body[10+n:]
+	}
+	return version, frames, data[10+size:]
+}
+
+func writeFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// tagged returns an ID3v2 tag of the given version holding frames, with
+// some padding, followed by the audio
+func tagged(version byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	body = append(body, make([]byte, 16)...)
+	data := append([]byte{'I', 'D', '3', version, 0, 0}, putSyncsafe(len(body))...)
+	data = append(data, body...)
+	return append(data, audio...)
+}
+
+func TestEmbed(t *testing.T) {
+	l := &Lyrics{Language: "en", Lines: []Line{{time.Second, "héllo"}, {2 * time.Second, "world"}}}
+	title := id3Frame("TIT2", 3, append([]byte{3}, "Song"...))
+	oldLyrics := id3Frame("USLT", 3, append([]byte{0}, "engold"...))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantVersion byte
+		wantKept    []string
+	}{
+		{"untagged file", audio, 3, nil},
+		{"keeps other frames", tagged(3, title), 3, []string{"TIT2"}},
+		{"replaces old lyrics", tagged(3, title, oldLyrics), 3, []string{"TIT2"}},
+		{"ID3v2.4", tagged(4, id3Frame("TIT2", 4, append([]byte{3}, "Song"...))), 4, []string{"TIT2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, tt.data)
+			if err := Embed(path, l); err != nil {
+				t.Fatalf("Embed() error = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			version, frames, rest := readTag(t, data)
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if !bytes.Equal(rest, audio) {
+				t.Errorf("audio = % x, want % x", rest, audio)
+			}
+			for _, id := range tt.wantKept {
+				if frames[id] == nil {
+					t.Errorf("frame %s was dropped", id)
+				}
+			}
+			if len(frames) != len(tt.wantKept)+2 {
+				t.Errorf("got %d frames, want %d", len(frames), len(tt.wantKept)+2)
+			}
+
+			wantUSLT := append([]byte{1}, "eng"...)
+			wantUSLT = append(wantUSLT, utf16Text("", true)...)
+			wantUSLT = append(wantUSLT, utf16Text("héllo\nworld", false)...)
+			if !bytes.Equal(frames["USLT"], wantUSLT) {
+				t.Errorf("USLT = % x, want % x", frames["USLT"], wantUSLT)
+			}
+
+			wantSYLT := append([]byte{1}, "eng"...)
+			wantSYLT = append(wantSYLT, 2, 1)
+			wantSYLT = append(wantSYLT, utf16Text("", true)...)
+			wantSYLT = append(wantSYLT, utf16Text("héllo", true)...)
+			wantSYLT = append(wantSYLT, 0, 0, 0x03, 0xe8)
+			wantSYLT = append(wantSYLT, utf16Text("world", true)...)
+			wantSYLT = append(wantSYLT, 0, 0, 0x07, 0xd0)
+			if !bytes.Equal(frames["SYLT"], wantSYLT) {
+				t.Errorf("SYLT = % x, want % x", frames["SYLT"], wantSYLT)
+			}
+		})
+	}
+}
+
+func TestEmbedRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"ID3v2.2", tagged(2)},
+		{"unsynchronised tag", append([]byte{'I', 'D', '3', 3, 0, 0x80, 0, 0, 0, 0}, audio...)},
+		{"truncated tag", []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, tt.data)
+			if err := Embed(path, &Lyrics{Lines: []Line{{0, "x"}}}); err == nil {
+				t.Error("Embed() succeeded")
+			}
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, tt.data) {
+				t.Error("Embed() changed the file")
+			}
+		})
+	}
+}
+
+func TestID3Language(t *testing.T) {
+	tests := map[string]string{
+		"en":    "eng",
+		"en-US": "eng",
+		"PT-br": "por",
+		"fil":   "fil",
+		"xx":    "XXX",
+		"":      "XXX",
+	}
+	for in, want := range tests {
+		if got := id3Language(in); got != want {
+			t.Errorf("id3Language(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSyncsafe(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 1024, 1<<28 - 1} {
+		if got := syncsafe(putSyncsafe(n)); got != n {
+			t.Errorf("syncsafe(putSyncsafe(%d)) = %d", n, got)
+		}
+	}
+	if got := putSyncsafe(257); !bytes.Equal(got, []byte{0, 0, 2, 1}) {
+		t.Errorf("putSyncsafe(257) = % x", got)
+	}
+}
@@ -0,0 +1,163 @@
+// Package lyrics turns YouTube subtitles into synced lyrics: LRC files and
+// the lyrics frames of ID3 tags.
+package lyrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLanguage is the default subtitle language
+	DefaultLanguage = "en"
+	// DefaultMinQuality is the default quality auto-captions need to be used
+	DefaultMinQuality = 60
+)
+
+// Options controls whether and which subtitles are made into lyrics
+type Options struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Language is the preferred subtitle language, e.g. "en"
+	Language string `json:"language,omitempty"`
+	// MinQuality is the lowest Quality of auto-captions that are used,
+	// 0-100; manual subtitles are always used
+	MinQuality int `json:"min_quality,omitempty"`
+}
+
+// Lang returns the subtitle language, DefaultLanguage when unset
+func (o Options) Lang() string {
+	if o.Language == "" {
+		return DefaultLanguage
+	}
+	return o.Language
+}
+
+// Accepts reports whether l is good enough to be saved
+func (o Options) Accepts(l *Lyrics) bool {
+	return len(l.Lines) > 0 && (!l.Auto || l.Quality >= o.MinQuality)
+}
+
+// Line is one lyrics line and when it is sung
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics are the lines of a subtitle track
+type Lyrics struct {
+	Lines []Line
+	// Language is the subtitle track's language code, e.g. "en"
+	Language string
+	// Auto is set for YouTube's auto-generated captions
+	Auto bool
+	// Quality rates auto-captions from 0 to 100; manual subtitles are 100
+	Quality int
+}
+
+// json3 is YouTube's JSON subtitle format
+type json3 struct {
+	Events []struct {
+		StartMs int64 `json:"tStartMs"`
+		Segs    []struct {
+			Text string `json:"utf8"`
+			// Auto-captions time each word and may rate the speech
+			// recognition's confidence in it, 0-255
+			OffsetMs   *int64 `json:"tOffsetMs"`
+			Confidence *int   `json:"acAsrConf"`
+		} `json:"segs"`
+	} `json:"events"`
+}
+
+var (
+	// cuePattern matches sound descriptions like [Music] or [Applause]
+	cuePattern   = regexp.MustCompile(`\[[^\]]*\]`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ParseJSON3 reads a subtitle track in YouTube's json3 format. Sound
+// descriptions and music notes are dropped, and so are lines repeated by
+// roll-up captions.
+func ParseJSON3(data []byte, language string) (*Lyrics, error) {
+	var track json3
+	if err := json.Unmarshal(data, &track); err != nil {
+		return nil, fmt.Errorf("parsing subtitles: %w", err)
+	}
+
+	l := &Lyrics{Language: language}
+	var confidence, rated, events, worded int
+	for _, event := range track.Events {
+		var text strings.Builder
+		for _, seg := range event.Segs {
+			text.WriteString(seg.Text)
+			if seg.OffsetMs != nil && *seg.OffsetMs > 0 {
+				l.Auto = true
+			}
+			// Tracks that don't rate their words leave it at 0
+			if seg.Confidence != nil && *seg.Confidence > 0 {
+				confidence += *seg.Confidence
+				rated++
+			}
+		}
+		if strings.TrimSpace(text.String()) == "" {
+			continue
+		}
+		events++
+
+		line := cleanLine(text.String())
+		if line == "" {
+			continue
+		}
+		worded++
+		if n := len(l.Lines); n > 0 && l.Lines[n-1].Text == line {
+			continue
+		}
+		l.Lines = append(l.Lines, Line{Time: time.Duration(event.StartMs) * time.Millisecond, Text: line})
+	}
+
+	switch {
+	case !l.Auto:
+		l.Quality = 100
+	case rated > 0:
+		l.Quality = confidence * 100 / (rated * 255)
+	case events > 0:
+		// Without confidences, rate how much of the track is words rather
+		// than [Music]; captions that hear nothing are useless as lyrics
+		l.Quality = worded * 100 / events
+	}
+	return l, nil
+}
+
+// cleanLine strips sound descriptions, music notes and extra whitespace
+func cleanLine(s string) string {
+	s = cuePattern.ReplaceAllString(s, " ")
+	s = strings.NewReplacer("♪", " ", "♫", " ", "♬", " ").Replace(s)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+// Text returns the lyrics without timestamps, one line per line
+func (l *Lyrics) Text() string {
+	lines := make([]string, len(l.Lines))
+	for i, line := range l.Lines {
+		lines[i] = line.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LRC returns the lyrics as an LRC file, with the artist, title and album
+// in its header when they are known
+func (l *Lyrics) LRC(artist, title, album string) []byte {
+	var b strings.Builder
+	for _, header := range []struct{ tag, value string }{{"ar", artist}, {"ti", title}, {"al", album}} {
+		if header.value != "" {
+			fmt.Fprintf(&b, "[%s:%s]\n", header.tag, header.value)
+		}
+	}
+	for _, line := range l.Lines {
+		cs := line.Time.Milliseconds() / 10
+		fmt.Fprintf(&b, "[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, line.Text)
+	}
+	return []byte(b.String())
+}
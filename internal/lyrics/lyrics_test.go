@@ -0,0 +1,147 @@
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJSON3(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantLines   []Line
+		wantAuto    bool
+		wantQuality int
+	}{
+		{
+			name: "manual subtitles",
+			data: `{"events":[
+				{"tStartMs":1500,"segs":[{"utf8":"Hello "},{"utf8":"world"}]},
+				{"tStartMs":4000,"segs":[{"utf8":"second  line\n"}]}]}`,
+			wantLines:   []Line{{1500 * time.Millisecond, "Hello world"}, {4 * time.Second, "second line"}},
+			wantQuality: 100,
+		},
+		{
+			name: "cues and music notes are dropped",
+			data: `{"events":[
+				{"tStartMs":0,"segs":[{"utf8":"[Music]"}]},
+				{"tStartMs":1000,"segs":[{"utf8":"♪ la la ♪"}]},
+				{"tStartMs":2000,"segs":[{"utf8":"[Applause] hey"}]}]}`,
+			wantLines:   []Line{{time.Second, "la la"}, {2 * time.Second, "hey"}},
+			wantQuality: 100,
+		},
+		{
+			name: "repeated lines are dropped",
+			data: `{"events":[
+				{"tStartMs":0,"segs":[{"utf8":"again"}]},
+				{"tStartMs":500,"segs":[{"utf8":"again"}]},
+				{"tStartMs":900,"segs":[{"utf8":"\n"}]},
+				{"tStartMs":1000,"segs":[{"utf8":"done"}]}]}`,
+			wantLines:   []Line{{0, "again"}, {time.Second, "done"}},
+			wantQuality: 100,
+		},
+		{
+			name: "rated auto-captions",
+			data: `{"events":[{"tStartMs":0,"segs":[
+				{"utf8":"one","acAsrConf":255},
+				{"utf8":" two","tOffsetMs":400,"acAsrConf":51}]}]}`,
+			wantLines:   []Line{{0, "one two"}},
+			wantAuto:    true,
+			wantQuality: 60,
+		},
+		{
+			name: "unrated auto-captions are rated by their words",
+			data: `{"events":[
+				{"tStartMs":0,"segs":[{"utf8":"[Music]"}]},
+				{"tStartMs":1000,"segs":[{"utf8":"[Music]"}]},
+				{"tStartMs":2000,"segs":[{"utf8":"[Music]"}]},
+				{"tStartMs":3000,"segs":[{"utf8":"la"},{"utf8":" la","tOffsetMs":200}]}]}`,
+			wantLines:   []Line{{3 * time.Second, "la la"}},
+			wantAuto:    true,
+			wantQuality: 25,
+		},
+		{
+			name:        "empty track",
+			data:        `{}`,
+			wantQuality: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := ParseJSON3([]byte(tt.data), "en")
+			if err != nil {
+				t.Fatalf("ParseJSON3() error = %v", err)
+			}
+			if !reflect.DeepEqual(l.Lines, tt.wantLines) {
+				t.Errorf("Lines = %q, want %q", l.Lines, tt.wantLines)
+			}
+			if l.Auto != tt.wantAuto || l.Quality != tt.wantQuality || l.Language != "en" {
+				t.Errorf("Auto, Quality, Language = %v, %d, %q; want %v, %d, \"en\"", l.Auto, l.Quality, l.Language, tt.wantAuto, tt.wantQuality)
+			}
+		})
+	}
+
+	if _, err := ParseJSON3([]byte("<transcript>"), "en"); err == nil {
+		t.Error("ParseJSON3() of a non-json3 track succeeded")
+	}
+}
+
+func TestLRC(t *testing.T) {
+	l := &Lyrics{Lines: []Line{
+		{1500 * time.Millisecond, "first"},
+		{61*time.Second + 234*time.Millisecond, "second"},
+		{10*time.Minute + 5*time.Second, "third"},
+	}}
+	tests := []struct {
+		name, artist, title, album string
+		want                       string
+	}{
+		{
+			name: "all headers", artist: "Artist", title: "Song", album: "Album",
+			want: "[ar:Artist]\n[ti:Song]\n[al:Album]\n[00:01.50]first\n[01:01.23]second\n[10:05.00]third\n",
+		},
+		{
+			name: "unknown headers are left out", title: "Song",
+			want: "[ti:Song]\n[00:01.50]first\n[01:01.23]second\n[10:05.00]third\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(l.LRC(tt.artist, tt.title, tt.album)); got != tt.want {
+				t.Errorf("LRC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := l.Text(); got != "first\nsecond\nthird" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	line := []Line{{0, "words"}}
+	tests := []struct {
+		name string
+		l    *Lyrics
+		want bool
+	}{
+		{"manual", &Lyrics{Lines: line, Quality: 100}, true},
+		{"good auto-captions", &Lyrics{Lines: line, Auto: true, Quality: 60}, true},
+		{"poor auto-captions", &Lyrics{Lines: line, Auto: true, Quality: 59}, false},
+		{"no lines", &Lyrics{Quality: 100}, false},
+	}
+	opts := Options{Enabled: true, MinQuality: 60}
+	for _, tt := range tests {
+		if got := opts.Accepts(tt.l); got != tt.want {
+			t.Errorf("Accepts(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := (Options{}).Lang(); got != DefaultLanguage {
+		t.Errorf("Lang() = %q, want %q", got, DefaultLanguage)
+	}
+	if got := (Options{Language: "de"}).Lang(); got != "de" {
+		t.Errorf("Lang() = %q, want de", got)
+	}
+}
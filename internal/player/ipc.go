@@ -0,0 +1,100 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ipcTimeout bounds every exchange with mpv so a hung player can't freeze
+// the UI
+const ipcTimeout = 300 * time.Millisecond
+
+// ipcClient talks to mpv's JSON IPC socket. mpv creates the socket a moment
+// after it starts, so the connection is made on first use.
+type ipcClient struct {
+	path string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+// ipcReply is mpv's answer to a command. Event lines have no request_id
+// and are skipped.
+type ipcReply struct {
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// get reads a property into v
+func (c *ipcClient) get(property string, v any) error {
+	return c.call(v, "get_property", property)
+}
+
+// call runs a command and decodes its result into v when v isn't nil
+func (c *ipcClient) call(v any, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := dialIPC(c.path)
+		if err != nil {
+			return fmt.Errorf("preview player not ready: %w", err)
+		}
+		c.conn = conn
+		c.reader = bufio.NewReader(conn)
+	}
+
+	c.nextID++
+	req, err := json.Marshal(map[string]any{"command": args, "request_id": c.nextID})
+	if err != nil {
+		return err
+	}
+	c.conn.SetDeadline(time.Now().Add(ipcTimeout))
+	if _, err := c.conn.Write(append(req, '\n')); err != nil {
+		c.reset()
+		return err
+	}
+
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			c.reset()
+			return err
+		}
+		var reply ipcReply
+		if json.Unmarshal(line, &reply) != nil || reply.RequestID != c.nextID {
+			continue
+		}
+		if reply.Error != "success" {
+			return fmt.Errorf("mpv: %s", reply.Error)
+		}
+		if v != nil {
+			return json.Unmarshal(reply.Data, v)
+		}
+		return nil
+	}
+}
+
+// reset drops a broken connection so the next call reconnects
+func (c *ipcClient) reset() {
+	c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+}
+
+// close disconnects and removes the socket file
+func (c *ipcClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.reset()
+	}
+	removeIPC(c.path)
+}
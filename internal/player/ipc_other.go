@@ -0,0 +1,18 @@
+//go:build !unix
+
+package player
+
+import (
+	"errors"
+	"net"
+)
+
+// ipcSupported is false where mpv's IPC uses named pipes, which aren't
+// supported yet; previews still play but can only be stopped
+const ipcSupported = false
+
+func dialIPC(path string) (net.Conn, error) {
+	return nil, errors.New("IPC not supported on this platform")
+}
+
+func removeIPC(path string) {}
@@ -0,0 +1,119 @@
+//go:build unix
+
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeMPV answers IPC commands on a unix socket like mpv does, sending an
+// event before every reply. It records the commands it received.
+func fakeMPV(t *testing.T, properties map[string]any) (string, <-chan []any) {
+	t.Helper()
+	// Socket paths are limited to ~100 bytes, too short for t.TempDir
+	dir, err := os.MkdirTemp("", "mpv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	commands := make(chan []any, 16)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var req struct {
+				Command   []any `json:"command"`
+				RequestID int   `json:"request_id"`
+			}
+			if json.Unmarshal(scanner.Bytes(), &req) != nil {
+				return
+			}
+			commands <- req.Command
+			reply := map[string]any{"request_id": req.RequestID, "error": "success"}
+			if req.Command[0] == "get_property" {
+				value, ok := properties[req.Command[1].(string)]
+				if ok {
+					reply["data"] = value
+				} else {
+					reply["error"] = "property unavailable"
+				}
+			}
+			event, _ := json.Marshal(map[string]any{"event": "playback-restart"})
+			line, _ := json.Marshal(reply)
+			conn.Write(append(append(event, '\n'), append(line, '\n')...))
+		}
+	}()
+	return path, commands
+}
+
+func TestPlayerControls(t *testing.T) {
+	path, commands := fakeMPV(t, map[string]any{"pause": true, "volume": 80.0, "time-pos": 12.5})
+	p := &Player{ipc: &ipcClient{path: path}}
+
+	status, err := p.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The duration isn't known yet
+	if want := (Status{Position: 12.5, Paused: true, Volume: 80}); status != want {
+		t.Errorf("Status() = %+v, want %+v", status, want)
+	}
+	for range 4 {
+		<-commands
+	}
+
+	if err := p.Seek(-10); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-commands, []any{"seek", -10.0, "relative"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Seek(-10) sent %v, want %v", got, want)
+	}
+	if err := p.AddVolume(5); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-commands, []any{"add", "volume", 5.0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AddVolume(5) sent %v, want %v", got, want)
+	}
+
+	p.ipc.close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("close() left the socket: %v", err)
+	}
+}
+
+func TestPlayerWithoutControls(t *testing.T) {
+	p := &Player{}
+	if p.Controllable() {
+		t.Error("Controllable() = true without an IPC socket")
+	}
+	if err := p.TogglePause(); !errors.Is(err, ErrNoControl) {
+		t.Errorf("TogglePause() = %v, want ErrNoControl", err)
+	}
+	if _, err := p.Status(); !errors.Is(err, ErrNoControl) {
+		t.Errorf("Status() = %v, want ErrNoControl", err)
+	}
+
+	// mpv may not have created its socket yet
+	p = &Player{ipc: &ipcClient{path: filepath.Join(t.TempDir(), "missing.sock")}}
+	if err := p.TogglePause(); err == nil {
+		t.Error("TogglePause() without a socket succeeded")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package player
+
+import (
+	"net"
+	"os"
+)
+
+// ipcSupported reports whether mpv's IPC socket can be used here
+const ipcSupported = true
+
+func dialIPC(path string) (net.Conn, error) {
+	return net.DialTimeout("unix", path, ipcTimeout)
+}
+
+// removeIPC deletes the socket file mpv leaves behind when killed
+func removeIPC(path string) {
+	os.Remove(path)
+}
@@ -0,0 +1,220 @@
+// Package player plays song previews with an external player. mpv is
+// started with a JSON IPC socket so playback can be paused, seeked and
+// turned up or down while it runs; other players can only be stopped.
+package player
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrNoControl is returned by playback controls when the player has no
+// IPC socket, e.g. because it isn't mpv
+var ErrNoControl = errors.New("the preview player can't be controlled; use mpv for playback controls")
+
+// Status is the playback state reported by the player
+type Status struct {
+	// Position is the elapsed time in seconds
+	Position float64
+	// Duration is the length in seconds, 0 while unknown
+	Duration float64
+	Paused   bool
+	// Volume is in percent, 100 being unchanged
+	Volume float64
+}
+
+// PreviewEndedMsg is sent when a preview stops playing, because the song
+// ended or it was stopped
+type PreviewEndedMsg struct {
+	Player *Player
+}
+
+// PreviewFailedMsg is sent when the player exits with an error, e.g.
+// because the video can't be streamed
+type PreviewFailedMsg struct {
+	Player *Player
+	Err    error
+}
+
+// Snippet defaults: 15 seconds from 30% into the song
+const (
+	DefaultSnippetLength = 15
+	DefaultSnippetOffset = 30
+)
+
+// Snippet limits a preview to part of a song
+type Snippet struct {
+	// Offset is where playback starts, in percent of the song
+	Offset float64
+	// Length is how many seconds are played
+	Length float64
+	// Duration is the song's length in seconds, used to turn Offset into
+	// a start time; 0 when unknown
+	Duration float64
+}
+
+// args returns the mpv options playing the snippet
+func (s Snippet) args() []string {
+	start := fmt.Sprintf("--start=%g%%", s.Offset)
+	if s.Duration > 0 {
+		start = fmt.Sprintf("--start=%d", int(s.Duration*s.Offset/100))
+	}
+	return []string{start, fmt.Sprintf("--length=%g", s.Length)}
+}
+
+// Player is a running preview
+type Player struct {
+	cmd *exec.Cmd
+	ipc *ipcClient
+	// stderr keeps the player's output for failure messages
+	stderr  bytes.Buffer
+	done    chan struct{}
+	err     error
+	stopped atomic.Bool
+}
+
+// sockets numbers the IPC sockets of this process
+var sockets atomic.Int64
+
+// Start plays url with command, the player and its arguments. mpv gets an
+// IPC socket for playback controls. A non-nil snippet plays only part of
+// the song, which needs mpv.
+func Start(command []string, url string, snippet *Snippet) (*Player, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no preview player configured")
+	}
+	args := append([]string{}, command[1:]...)
+	if snippet != nil {
+		if !isMPV(command[0]) {
+			return nil, errors.New("playing snippets needs mpv as the preview player")
+		}
+		args = append(args, snippet.args()...)
+	}
+
+	p := &Player{}
+	if isMPV(command[0]) && ipcSupported {
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("music-download-mpv-%d-%d.sock", os.Getpid(), sockets.Add(1)))
+		args = append(args, "--input-ipc-server="+path)
+		p.ipc = &ipcClient{path: path}
+	}
+	args = append(args, url)
+
+	p.cmd = exec.Command(command[0], args...)
+	p.cmd.Stderr = &p.stderr
+	// Don't hang on pipes held open by children that outlive the player
+	p.cmd.WaitDelay = time.Second
+	setProcessGroup(p.cmd)
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting preview player: %w", err)
+	}
+	p.done = make(chan struct{})
+	go func() {
+		p.err = p.cmd.Wait()
+		if p.ipc != nil {
+			p.ipc.close()
+		}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Wait returns a command that waits for the player to exit and reports
+// it as a PreviewEndedMsg or PreviewFailedMsg
+func (p *Player) Wait() tea.Cmd {
+	return func() tea.Msg {
+		<-p.done
+		if p.err != nil && !p.stopped.Load() {
+			return PreviewFailedMsg{Player: p, Err: p.failure()}
+		}
+		return PreviewEndedMsg{Player: p}
+	}
+}
+
+// failure describes why the player exited, using the last line it
+// printed to stderr when there is one
+func (p *Player) failure() error {
+	lines := strings.Split(strings.TrimSpace(p.stderr.String()), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return errors.New(last)
+	}
+	return p.err
+}
+
+// Controllable reports whether playback controls are available
+func (p *Player) Controllable() bool {
+	return p.ipc != nil
+}
+
+// Stop kills the player and whatever it started, e.g. mpv's yt-dlp. A
+// player that already exited is left alone: its process ID may have been
+// reused.
+func (p *Player) Stop() {
+	p.stopped.Store(true)
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	killProcessGroup(p.cmd)
+}
+
+// TogglePause pauses or resumes playback
+func (p *Player) TogglePause() error {
+	return p.command("cycle", "pause")
+}
+
+// Seek moves playback by seconds, backwards when negative
+func (p *Player) Seek(seconds float64) error {
+	return p.command("seek", seconds, "relative")
+}
+
+// SeekPercent jumps to percent of the song
+func (p *Player) SeekPercent(percent float64) error {
+	return p.command("seek", percent, "absolute-percent")
+}
+
+// AddVolume changes the volume by delta percent
+func (p *Player) AddVolume(delta float64) error {
+	return p.command("add", "volume", delta)
+}
+
+// Status returns the current playback state. Position and Duration stay 0
+// while the song is still loading.
+func (p *Player) Status() (Status, error) {
+	if p.ipc == nil {
+		return Status{}, ErrNoControl
+	}
+	var s Status
+	if err := p.ipc.get("pause", &s.Paused); err != nil {
+		return Status{}, err
+	}
+	if err := p.ipc.get("volume", &s.Volume); err != nil {
+		return Status{}, err
+	}
+	// Unavailable until mpv has opened the stream
+	p.ipc.get("time-pos", &s.Position)
+	p.ipc.get("duration", &s.Duration)
+	return s, nil
+}
+
+func (p *Player) command(args ...any) error {
+	if p.ipc == nil {
+		return ErrNoControl
+	}
+	return p.ipc.call(nil, args...)
+}
+
+// isMPV reports whether the player binary is mpv
+func isMPV(binary string) bool {
+	name := filepath.Base(binary)
+	return name == "mpv" || name == "mpv.exe"
+}
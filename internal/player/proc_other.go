@@ -0,0 +1,13 @@
+//go:build !unix
+
+package player
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups aren't available
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the player
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package player
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the player in its own process group, so stopping
+// it also stops the processes it spawns and terminal signals meant for the
+// TUI don't reach it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the player and every process in its group
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
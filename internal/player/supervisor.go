@@ -0,0 +1,68 @@
+package player
+
+import (
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Supervisor owns the preview player process. At most one preview plays at
+// a time, and Stop kills it however the program exits.
+type Supervisor struct {
+	command []string
+
+	mu      sync.Mutex
+	current *Player
+}
+
+// NewSupervisor returns a supervisor playing previews with command, the
+// player and its arguments
+func NewSupervisor(command []string) *Supervisor {
+	return &Supervisor{command: command}
+}
+
+// Play stops the current preview and plays url. The returned command
+// reports when the new preview ends or fails.
+func (s *Supervisor) Play(url string) (*Player, tea.Cmd, error) {
+	return s.play(url, nil)
+}
+
+// PlaySnippet is like Play but only plays part of the song
+func (s *Supervisor) PlaySnippet(url string, snippet Snippet) (*Player, tea.Cmd, error) {
+	return s.play(url, &snippet)
+}
+
+func (s *Supervisor) play(url string, snippet *Snippet) (*Player, tea.Cmd, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil {
+		s.current.Stop()
+		s.current = nil
+	}
+	p, err := Start(s.command, url, snippet)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.current = p
+	// Forget the player once it exits on its own, so Stop doesn't signal
+	// a process group that no longer exists
+	go func() {
+		<-p.done
+		s.mu.Lock()
+		if s.current == p {
+			s.current = nil
+		}
+		s.mu.Unlock()
+	}()
+	return p, p.Wait(), nil
+}
+
+// Stop kills the current preview, if any
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil {
+		s.current.Stop()
+		s.current = nil
+	}
+}
@@ -0,0 +1,197 @@
+//go:build unix
+
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// waitMsg runs a player's wait command, failing the test if the player
+// doesn't exit within a few seconds
+func waitMsg(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	msgs := make(chan tea.Msg, 1)
+	go func() { msgs <- cmd() }()
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("the player is still running")
+		return nil
+	}
+}
+
+// alive reports whether the process pid runs, counting zombies as gone
+func alive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	_, rest, _ := strings.Cut(string(stat), ") ")
+	return !strings.HasPrefix(rest, "Z")
+}
+
+// currentPlayer returns the supervisor's current preview
+func (s *Supervisor) currentPlayer() *Player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	// The URL is sleep's argument
+	s := NewSupervisor([]string{"sleep"})
+	defer s.Stop()
+
+	first, wait, err := s.Play("30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, wait2, err := s.Play("30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, ok := waitMsg(t, wait).(PreviewEndedMsg); !ok || msg.Player != first {
+		t.Errorf("first preview sent %#v, want PreviewEndedMsg", msg)
+	}
+	if s.currentPlayer() != second {
+		t.Error("the second preview isn't the current one")
+	}
+
+	s.Stop()
+	if msg, ok := waitMsg(t, wait2).(PreviewEndedMsg); !ok || msg.Player != second {
+		t.Errorf("stopped preview sent %#v, want PreviewEndedMsg", msg)
+	}
+	if s.currentPlayer() != nil {
+		t.Error("Stop kept the preview")
+	}
+	// Stopping twice is harmless
+	s.Stop()
+}
+
+func TestSupervisorStopKillsChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// Like mpv starting yt-dlp: the player waits on a child of its own
+	s := NewSupervisor([]string{"sh", "-c", `sleep 30 & echo $! > "$0"; wait`})
+	_, wait, err := s.Play(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pid int
+	for deadline := time.Now().Add(5 * time.Second); pid == 0; {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		if pid == 0 && time.Now().After(deadline) {
+			t.Fatal("the player didn't start its child")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Stop()
+	waitMsg(t, wait)
+	for deadline := time.Now().Add(5 * time.Second); alive(pid); {
+		if time.Now().After(deadline) {
+			t.Fatal("the player's child outlived Stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSupervisorPreviewEnds(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		wantErr string
+	}{
+		{"ends", []string{"true"}, ""},
+		{"fails", []string{"sh", "-c", "echo first >&2; echo stream unavailable >&2; exit 1"}, "stream unavailable"},
+		{"fails silently", []string{"false"}, "exit status 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSupervisor(tt.command)
+			p, wait, err := s.Play("url")
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch msg := waitMsg(t, wait).(type) {
+			case PreviewEndedMsg:
+				if tt.wantErr != "" {
+					t.Errorf("got PreviewEndedMsg, want failure %q", tt.wantErr)
+				}
+			case PreviewFailedMsg:
+				if msg.Player != p || msg.Err.Error() != tt.wantErr {
+					t.Errorf("got failure %v, want %q", msg.Err, tt.wantErr)
+				}
+			}
+
+			// The supervisor forgets a preview that exited on its own
+			for deadline := time.Now().Add(5 * time.Second); s.currentPlayer() != nil; {
+				if time.Now().After(deadline) {
+					t.Fatal("the supervisor kept the exited preview")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			s.Stop()
+		})
+	}
+}
+
+func TestSupervisorStartErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		snippet bool
+		wantErr string
+	}{
+		{"no command", nil, false, "no preview player configured"},
+		{"missing binary", []string{filepath.Join(t.TempDir(), "mpv")}, false, "starting preview player"},
+		{"snippet without mpv", []string{"sleep"}, true, "needs mpv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSupervisor(tt.command)
+			var err error
+			if tt.snippet {
+				_, _, err = s.PlaySnippet("30", Snippet{Offset: 30, Length: 15})
+			} else {
+				_, _, err = s.Play("30")
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+			if s.currentPlayer() != nil {
+				t.Error("a failed start left a current preview")
+			}
+		})
+	}
+}
+
+func TestSnippetArgs(t *testing.T) {
+	tests := []struct {
+		snippet Snippet
+		want    string
+	}{
+		{Snippet{Offset: 30, Length: 15}, "--start=30% --length=15"},
+		{Snippet{Offset: 30, Length: 15, Duration: 200}, "--start=60 --length=15"},
+		{Snippet{Offset: 0, Length: 7.5, Duration: 200}, "--start=0 --length=7.5"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.snippet.args(), " "); got != tt.want {
+			t.Errorf("%+v.args() = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package resume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// failureJSON is one failed item in an exported failure report
+type failureJSON struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+	Query    string `json:"query,omitempty"`
+	Category string `json:"category,omitempty"`
+	Error    string `json:"error"`
+}
+
+// reportJSON is the exported failure report of a job
+type reportJSON struct {
+	Playlist    string        `json:"playlist,omitempty"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Total       int           `json:"total"`
+	Failed      int           `json:"failed"`
+	Failures    []failureJSON `json:"failures"`
+}
+
+// ExportFailures writes the job's failed items into dir as a JSON report
+// and as a plain list of URLs that can be downloaded again with the batch
+// command. It returns the paths of both files.
+func (j *Job) ExportFailures(dir string) (reportPath, listPath string, err error) {
+	report := reportJSON{
+		Playlist:    j.Name,
+		GeneratedAt: time.Now(),
+		Total:       len(j.Items),
+		Failures:    []failureJSON{},
+	}
+	list := fmt.Sprintf("# Failed downloads from %s\n", j.Title())
+	for i, item := range j.Items {
+		if item.Status != StatusFailed {
+			continue
+		}
+		f := failureJSON{
+			Index:    i + 1,
+			ID:       item.ID,
+			Title:    item.Title,
+			Query:    item.Query,
+			Category: item.Category,
+			Error:    item.Error,
+		}
+		if item.ID != "" {
+			f.URL = youtube.VideoURL(item.ID)
+			list += f.URL + "\n"
+		} else {
+			// Unresolved batch entries are written back as search queries
+			list += item.Query + "\n"
+		}
+		report.Failures = append(report.Failures, f)
+	}
+	report.Failed = len(report.Failures)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("creating report directory: %w", err)
+	}
+
+	base := filepath.Join(dir, "failures-"+j.fileStem()+"-"+report.GeneratedAt.Format("20060102-150405"))
+	reportPath, listPath = base+".json", base+".txt"
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0o644); err != nil {
+		return "", "", fmt.Errorf("writing failure report: %w", err)
+	}
+	if err := os.WriteFile(listPath, []byte(list), 0o644); err != nil {
+		return "", "", fmt.Errorf("writing failure list: %w", err)
+	}
+	return reportPath, listPath, nil
+}
+
+// fileStem returns a filename-safe version of the job's title
+func (j *Job) fileStem() string {
+	words := strings.FieldsFunc(j.Title(), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if len(words) == 0 {
+		return "playlist"
+	}
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,228 @@
+// Package resume saves playlist download progress to disk so unfinished
+// jobs can be picked up again after the app quits or crashes.
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+// Status is the outcome of a single job item
+type Status string
+
+const (
+	StatusPending Status = ""
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// Item is a playlist item and how its download went
+type Item struct {
+	ID      string `json:"id,omitempty"`
+	Title   string `json:"title"`
+	Channel string `json:"channel,omitempty"`
+	Query   string `json:"query,omitempty"`
+	Status  Status `json:"status,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Reason says why a skipped item wasn't downloaded
+	Reason string `json:"reason,omitempty"`
+	// Warning describes a non-fatal problem with a downloaded item
+	Warning string `json:"warning,omitempty"`
+	// Category classifies Error, see youtube.ErrorCategory
+	Category string `json:"category,omitempty"`
+}
+
+// Job is the saved state of a playlist download
+type Job struct {
+	ID           string                  `json:"id"`
+	Name         string                  `json:"name"`
+	Options      youtube.DownloadOptions `json:"options"`
+	SkipArchived bool                    `json:"skip_archived"`
+	Album        *youtube.Album          `json:"album,omitempty"`
+	Items        []Item                  `json:"items"`
+	StartedAt    time.Time               `json:"started_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+// NewJob returns the state of a playlist download that hasn't started yet
+func NewJob(name string, items []youtube.SearchResult, opts youtube.DownloadOptions, skipArchived bool) *Job {
+	now := time.Now()
+	job := &Job{
+		ID:           strconv.FormatInt(now.UnixNano(), 36),
+		Name:         name,
+		Options:      opts,
+		SkipArchived: skipArchived,
+		Items:        make([]Item, len(items)),
+		StartedAt:    now,
+		UpdatedAt:    now,
+	}
+	for i, r := range items {
+		job.Items[i] = Item{ID: r.ID, Title: r.Title, Channel: r.Channel, Query: r.Query}
+	}
+	return job
+}
+
+// Record stores the outcome of a finished item
+func (j *Job) Record(msg youtube.PlaylistDownloadProgressMsg) {
+	if msg.Index < 0 || msg.Index >= len(j.Items) {
+		return
+	}
+	item := &j.Items[msg.Index]
+	if msg.ID != "" {
+		item.ID = msg.ID
+	}
+	if msg.Title != "" {
+		item.Title = msg.Title
+	}
+	item.Path = msg.Path
+	item.Error = msg.Error
+	item.Reason = msg.SkipReason
+	item.Warning = msg.Warning
+	item.Category = string(msg.Category)
+	switch {
+	case msg.Skipped:
+		item.Status = StatusSkipped
+	case msg.Success:
+		item.Status = StatusDone
+	default:
+		item.Status = StatusFailed
+	}
+	j.UpdatedAt = time.Now()
+}
+
+// SearchResults returns the job's items in playlist order
+func (j *Job) SearchResults() []youtube.SearchResult {
+	results := make([]youtube.SearchResult, len(j.Items))
+	for i, item := range j.Items {
+		results[i] = youtube.SearchResult{ID: item.ID, Title: item.Title, Channel: item.Channel, Query: item.Query}
+	}
+	return results
+}
+
+// Unfinished returns the positions of items that still need downloading.
+// Failed items are included so resuming retries them.
+func (j *Job) Unfinished() []int {
+	indexes := []int{}
+	for i, item := range j.Items {
+		if item.Status != StatusDone && item.Status != StatusSkipped {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Count returns the number of items with the given status
+func (j *Job) Count(status Status) int {
+	n := 0
+	for _, item := range j.Items {
+		if item.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Title names the job for display
+func (j *Job) Title() string {
+	if j.Name == "" {
+		return "Selected songs"
+	}
+	return j.Name
+}
+
+// Store keeps one JSON file per unfinished job in a directory. A nil
+// *Store has no jobs and ignores saves.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store that keeps jobs in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save writes the job's current state. The file is replaced atomically so
+// a crash mid-write never leaves a truncated job behind.
+func (s *Store) Save(j *Job) error {
+	if s == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("creating jobs directory: %w", err)
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := s.path(j.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("saving job: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("saving job: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a job, e.g. once it has finished
+func (s *Store) Remove(id string) error {
+	if s == nil {
+		return nil
+	}
+	err := os.Remove(s.path(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing job: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved jobs, oldest first. Unreadable files are skipped.
+func (s *Store) List() ([]*Job, error) {
+	if s == nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading jobs directory: %w", err)
+	}
+
+	var jobs []*Job
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var j Job
+		if err := json.Unmarshal(data, &j); err != nil || j.ID == "" {
+			continue
+		}
+		jobs = append(jobs, &j)
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].StartedAt.Before(jobs[b].StartedAt)
+	})
+	return jobs, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
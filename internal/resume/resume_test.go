@@ -0,0 +1,147 @@
+package resume
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelapazborrero/music_download/internal/youtube"
+)
+
+func TestRecord(t *testing.T) {
+	items := []youtube.SearchResult{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {Query: "artist - song"}}
+	tests := []struct {
+		name string
+		msg  youtube.PlaylistDownloadProgressMsg
+		want Item
+	}{
+		{
+			name: "done",
+			msg:  youtube.PlaylistDownloadProgressMsg{Index: 0, Title: "A", Success: true, Path: "A.mp3"},
+			want: Item{ID: "a", Title: "A", Status: StatusDone, Path: "A.mp3"},
+		},
+		{
+			name: "done with a warning",
+			msg:  youtube.PlaylistDownloadProgressMsg{Index: 1, Success: true, Path: "B.mp3", Warning: "cover art: no thumbnail"},
+			want: Item{ID: "b", Status: StatusDone, Path: "B.mp3", Warning: "cover art: no thumbnail"},
+		},
+		{
+			name: "failed",
+			msg:  youtube.PlaylistDownloadProgressMsg{Index: 2, Error: "private", Category: youtube.CategoryPrivate},
+			want: Item{ID: "c", Status: StatusFailed, Error: "private", Category: string(youtube.CategoryPrivate)},
+		},
+		{
+			name: "skipped",
+			msg:  youtube.PlaylistDownloadProgressMsg{Index: 3, Skipped: true, SkipReason: "already downloaded"},
+			want: Item{ID: "d", Status: StatusSkipped, Reason: "already downloaded"},
+		},
+		{
+			name: "searched item learns its video",
+			msg:  youtube.PlaylistDownloadProgressMsg{Index: 4, ID: "e", Title: "Song", Success: true},
+			want: Item{ID: "e", Title: "Song", Query: "artist - song", Status: StatusDone},
+		},
+	}
+	job := NewJob("Mix", items, youtube.DownloadOptions{}, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job.Record(tt.msg)
+			if got := job.Items[tt.msg.Index]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Record() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	job.Record(youtube.PlaylistDownloadProgressMsg{Index: 99, Success: true})
+	if got := job.Unfinished(); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Unfinished() = %v, want [2]", got)
+	}
+	if job.Count(StatusDone) != 3 || job.Count(StatusFailed) != 1 || job.Count(StatusSkipped) != 1 {
+		t.Errorf("Count() = %d done, %d failed, %d skipped", job.Count(StatusDone), job.Count(StatusFailed), job.Count(StatusSkipped))
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	store := NewStore(dir)
+
+	older := NewJob("Older", []youtube.SearchResult{{ID: "a"}}, youtube.DownloadOptions{}, false)
+	older.StartedAt = time.Now().Add(-time.Hour)
+	newer := NewJob("", []youtube.SearchResult{{ID: "b"}}, youtube.DownloadOptions{}, true)
+	newer.ID += "x"
+	for _, j := range []*Job{newer, older} {
+		if err := store.Save(j); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	// Files that aren't jobs are skipped
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0o644)
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Title() != "Older" || jobs[1].Title() != "Selected songs" || !jobs[1].SkipArchived {
+		t.Fatalf("List() = %+v, want the older job first", jobs)
+	}
+
+	if err := store.Remove(older.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := store.Remove(older.ID); err != nil {
+		t.Errorf("Remove() of a removed job error = %v", err)
+	}
+	if jobs, _ := store.List(); len(jobs) != 1 {
+		t.Errorf("List() after Remove = %d jobs, want 1", len(jobs))
+	}
+
+	var none *Store
+	if err := none.Save(older); err != nil {
+		t.Errorf("nil Store Save() error = %v", err)
+	}
+	if jobs, err := none.List(); jobs != nil || err != nil {
+		t.Errorf("nil Store List() = %v, %v", jobs, err)
+	}
+}
+
+func TestExportFailures(t *testing.T) {
+	items := []youtube.SearchResult{{ID: "aaaaaaaaaaa", Title: "Good"}, {ID: "bbbbbbbbbbb", Title: "Hidden"}, {Title: "song", Query: "some song"}}
+	job := NewJob("Road Trip: 2024", items, youtube.DownloadOptions{}, false)
+	job.Record(youtube.PlaylistDownloadProgressMsg{Index: 0, Success: true})
+	job.Record(youtube.PlaylistDownloadProgressMsg{Index: 1, Error: "Private video", Category: youtube.CategoryPrivate})
+	job.Record(youtube.PlaylistDownloadProgressMsg{Index: 2, Error: "no results"})
+
+	dir := t.TempDir()
+	reportPath, listPath, err := job.ExportFailures(dir)
+	if err != nil {
+		t.Fatalf("ExportFailures() error = %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(reportPath), "failures-Road-Trip-2024-") || filepath.Dir(listPath) != dir {
+		t.Errorf("ExportFailures() wrote %s and %s", reportPath, listPath)
+	}
+
+	list, err := os.ReadFile(listPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Failed downloads from Road Trip: 2024\nhttps://www.youtube.com/watch?v=bbbbbbbbbbb\nsome song\n"
+	if string(list) != want {
+		t.Errorf("failure list = %q, want %q", list, want)
+	}
+
+	var report reportJSON
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 3 || report.Failed != 2 || report.Failures[0].Index != 2 || report.Failures[0].Category != "private" {
+		t.Errorf("report = %+v", report)
+	}
+}
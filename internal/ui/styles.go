@@ -23,6 +23,17 @@ var (
 	DetailStyle = lipgloss.NewStyle().
 			Padding(0, 2)
 
+	StatusStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7D56F4")).
+			Padding(0, 2)
+
+	OwnedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#04B575"))
+
+	NowPlayingStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F25D94")).
+			Bold(true)
+
 	ErrorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FF0000")).
 			Bold(true)
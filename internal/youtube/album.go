@@ -0,0 +1,62 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AlbumTemplate is the filename template of album downloads: one folder
+// named after the album, with the tracks in playlist order
+const AlbumTemplate = "{album}/{index} - {title}"
+
+// Album describes a playlist downloaded as an album
+type Album struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+}
+
+// NewAlbum derives an album from a playlist. The "Album - " prefix of
+// YouTube Music album playlists is dropped, and the album artist is the
+// artist most tracks are credited to.
+func NewAlbum(name string, items []SearchResult) Album {
+	album := Album{Title: strings.TrimSpace(strings.TrimPrefix(name, "Album - "))}
+
+	counts := map[string]int{}
+	for _, item := range items {
+		if item.ID == "" {
+			continue
+		}
+		track := ParseTitle(item.Title, item.Channel)
+		if len(track.Artists) == 0 {
+			continue
+		}
+		artist := track.Artists[0]
+		counts[artist]++
+		if counts[artist] > counts[album.Artist] {
+			album.Artist = artist
+		}
+	}
+	return album
+}
+
+// String describes the album as "Title by Artist"
+func (a Album) String() string {
+	if a.Artist == "" {
+		return a.Title
+	}
+	return a.Title + " by " + a.Artist
+}
+
+// Track returns req as track index (1-based) of total on the album. The
+// album tags and track number replace any in req.Tags and the file goes
+// into the album folder.
+func (a Album) Track(req DownloadRequest, index, total int) DownloadRequest {
+	req.Template = AlbumTemplate
+	req.Tags.Album = a.Title
+	req.Tags.AlbumArtist = a.Artist
+	if index > 0 {
+		req.PlaylistIndex = index
+		req.Tags.Track = fmt.Sprintf("%d/%d", index, total)
+	}
+	return req
+}
@@ -0,0 +1,62 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backend is a media extractor capable of searching, inspecting and
+// downloading videos. The yt-dlp implementation is used by default; other
+// extractors (or a scripted fake for offline testing) can be swapped in.
+// Every call stops early when its ctx is cancelled.
+type Backend interface {
+	// Search returns up to limit results for the given query
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	// Metadata retrieves detailed metadata for a single video
+	Metadata(ctx context.Context, videoID string) (*VideoMetadata, error)
+	// Download fetches a video's audio as described by req, reporting
+	// progress to the optional progress callback. It returns the path of
+	// the written file.
+	Download(ctx context.Context, req DownloadRequest, progress ProgressFunc) (string, error)
+	// Playlist lists a playlist and every item in it
+	Playlist(ctx context.Context, playlistID string) (*Playlist, error)
+	// Thumbnail saves a video's thumbnail as a JPEG image at path
+	Thumbnail(ctx context.Context, videoID, path string) error
+}
+
+// DownloadRequest describes a single audio download
+type DownloadRequest struct {
+	VideoID string
+	Title   string
+	// Channel is the uploading channel. Together with Title it gives the
+	// artist and song title written as tags; when Title is missing they
+	// come from the downloaded video's own metadata.
+	Channel string
+	// Tags replace the tags parsed from Title, e.g. after editing them
+	Tags Tags
+	// Cover, when set, is a JPEG image embedded as cover art instead of
+	// the video thumbnail, e.g. one shared by every track of an album
+	Cover string
+	// Playlist and PlaylistIndex (1-based) fill the {playlist} and {index}
+	// filename tokens when downloading playlist items
+	Playlist      string
+	PlaylistIndex int
+	DownloadOptions
+}
+
+// Playlist is a YouTube playlist and its items
+type Playlist struct {
+	ID    string
+	Title string
+	Items []SearchResult
+}
+
+// VideoURL builds the canonical watch URL for a video ID
+func VideoURL(videoID string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
+}
+
+// PlaylistURL builds the canonical URL for a playlist ID
+func PlaylistURL(playlistID string) string {
+	return fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID)
+}
@@ -0,0 +1,78 @@
+package youtube
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadBatch reads batch entries from r: one video URL, playlist URL or
+// free-text search query per line. Blank lines and lines starting with #
+// are ignored.
+func ReadBatch(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading batch: %w", err)
+	}
+	return entries, nil
+}
+
+// ResolveBatch turns batch entries into download items. Playlist URLs are
+// expanded into their items, video URLs are used as-is (a watch URL that
+// also names a playlist is just the video) and anything else becomes a
+// query that the playlist workers resolve to its top search result. A
+// playlist that can't be fetched or is empty yields a single item failing
+// with that error, so it is reported with the other failures.
+func ResolveBatch(ctx context.Context, b Backend, entries []string) []SearchResult {
+	var items []SearchResult
+	for _, entry := range entries {
+		if videoID := ExtractVideoID(entry); videoID != "" {
+			items = append(items, SearchResult{Title: entry, ID: videoID})
+			continue
+		}
+
+		if playlistID := ExtractPlaylistID(entry); playlistID != "" {
+			playlist, err := b.Playlist(ctx, playlistID)
+			switch {
+			case err != nil:
+				items = append(items, SearchResult{Title: entry, err: fmt.Errorf("failed to fetch playlist: %w", err)})
+			case len(playlist.Items) == 0:
+				items = append(items, SearchResult{Title: entry, err: fmt.Errorf("no items found in playlist")})
+			default:
+				items = append(items, playlist.Items...)
+			}
+			continue
+		}
+
+		items = append(items, SearchResult{Title: entry, Query: entry})
+	}
+	return items
+}
+
+// resolveItem looks up the top search result for an item that only has a query
+func resolveItem(ctx context.Context, b Backend, item SearchResult) (SearchResult, error) {
+	if item.err != nil {
+		return item, item.err
+	}
+	if item.Query == "" {
+		return item, fmt.Errorf("could not resolve %q", item.Title)
+	}
+	results, err := b.Search(ctx, item.Query, 1)
+	if err != nil {
+		return item, fmt.Errorf("search failed: %w", err)
+	}
+	if len(results) == 0 {
+		return item, fmt.Errorf("no results for %q", item.Query)
+	}
+	return results[0], nil
+}
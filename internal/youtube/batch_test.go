@@ -0,0 +1,104 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+	input := "# my songs\nhttps://youtu.be/abc123def45\n\n  lofi beats  \n#skip\nhttps://www.youtube.com/playlist?list=PL1\n"
+	got, err := ReadBatch(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://youtu.be/abc123def45", "lofi beats", "https://www.youtube.com/playlist?list=PL1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadBatch() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveBatch(t *testing.T) {
+	fake := NewFakeBackend()
+	fake.Playlists["PL1"] = Playlist{ID: "PL1", Items: []SearchResult{{Title: "One", ID: "aaaaaaaaaaa"}, {Title: "Two", ID: "bbbbbbbbbbb"}}}
+	fake.Playlists["PLempty"] = Playlist{ID: "PLempty"}
+
+	items := ResolveBatch(context.Background(), fake, []string{
+		"https://www.youtube.com/playlist?list=PL1",
+		"https://www.youtube.com/watch?v=ccccccccccc&list=PL1",
+		"https://youtu.be/ddddddddddd",
+		"some song",
+		"hello adele",
+		"daft punk 1",
+		"lofi beats!",
+		"eeeeeeeeeee",
+		"https://www.youtube.com/playlist?list=PLmissing",
+		"https://www.youtube.com/playlist?list=PLempty",
+	})
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	wantIDs := []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "ddddddddddd", "", "", "", "", "eeeeeeeeeee", "", ""}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Fatalf("ResolveBatch() IDs = %q, want %q", ids, wantIDs)
+	}
+	// Free text is searched, even when it is as long as a video ID
+	for i, query := range []string{"some song", "hello adele", "daft punk 1", "lofi beats!"} {
+		if items[4+i].Query != query {
+			t.Errorf("query entry = %+v, want Query %q", items[4+i], query)
+		}
+	}
+
+	// Unresolvable entries fail with the reason once downloaded
+	if _, err := resolveItem(context.Background(), fake, items[9]); !errors.Is(err, ErrRemoved) {
+		t.Errorf("missing playlist resolves with %v, want ErrRemoved", err)
+	}
+	if _, err := resolveItem(context.Background(), fake, items[10]); err == nil || !strings.Contains(err.Error(), "no items") {
+		t.Errorf("empty playlist resolves with %v, want an empty playlist error", err)
+	}
+}
+
+func TestResolveItem(t *testing.T) {
+	fake := NewFakeBackend()
+	fake.SearchResults["song"] = []SearchResult{{Title: "Artist - Song", ID: "abc123def45"}, {Title: "Other", ID: "zzzzzzzzzzz"}}
+
+	got, err := resolveItem(context.Background(), fake, SearchResult{Title: "song", Query: "song"})
+	if err != nil || got.ID != "abc123def45" {
+		t.Errorf("resolveItem(song) = %+v, %v, want the top result", got, err)
+	}
+	if _, err := resolveItem(context.Background(), fake, SearchResult{Title: "nothing", Query: "nothing"}); err == nil {
+		t.Error("resolveItem(nothing) succeeded without results")
+	}
+}
+
+func TestExtractIDs(t *testing.T) {
+	tests := []struct {
+		input    string
+		video    string
+		playlist string
+	}{
+		{"abc123def45", "abc123def45", ""},
+		{"https://www.youtube.com/watch?v=abc123def45", "abc123def45", ""},
+		{"https://m.youtube.com/watch?v=abc123def45&t=42s", "abc123def45", ""},
+		{"https://youtu.be/abc123def45?si=xyz", "abc123def45", ""},
+		{"https://www.youtube.com/embed/abc123def45", "abc123def45", ""},
+		{"https://www.youtube.com/watch?v=abc123def45&list=PL123", "abc123def45", "PL123"},
+		{"https://www.youtube.com/playlist?list=PL123&si=xyz", "", "PL123"},
+		{"lofi hip hop", "", ""},
+		{"hello adele", "", ""},
+		{"lofi beats!", "", ""},
+		{"abc_12-ef45", "abc_12-ef45", ""},
+	}
+	for _, tt := range tests {
+		if got := ExtractVideoID(tt.input); got != tt.video {
+			t.Errorf("ExtractVideoID(%q) = %q, want %q", tt.input, got, tt.video)
+		}
+		if got := ExtractPlaylistID(tt.input); got != tt.playlist {
+			t.Errorf("ExtractPlaylistID(%q) = %q, want %q", tt.input, got, tt.playlist)
+		}
+	}
+}
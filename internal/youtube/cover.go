@@ -0,0 +1,164 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adelapazborrero/music_download/internal/coverart"
+)
+
+// Thumbnail saves a video's thumbnail as a JPEG image at path
+func (y *YTDLPBackend) Thumbnail(ctx context.Context, videoID, path string) error {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	_, err := y.output(ctx,
+		"--skip-download",
+		"--write-thumbnail",
+		"--convert-thumbnails", "jpg",
+		"--quiet",
+		"--no-warnings",
+		"-o", "thumbnail:"+ytdlpEscape(base)+".%(ext)s",
+		VideoURL(videoID),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no thumbnail for %s", videoID)
+	}
+	return nil
+}
+
+// writesCoverFile reports whether req saves a cover image next to the file,
+// which is only done for playlist items saved into a folder named after the
+// album or playlist. A folder shared with other downloads would keep the
+// first playlist's cover forever.
+func (req DownloadRequest) writesCoverFile() bool {
+	if req.CoverFile == "" || req.PlaylistIndex == 0 {
+		return false
+	}
+	dir := filepath.Dir(req.template())
+	return strings.Contains(dir, "{album}") || strings.Contains(dir, "{playlist}")
+}
+
+// finishCover embeds cover art into the downloaded file at path and saves
+// the playlist's cover file. The cover is req.Cover, or else made from the
+// thumbnail yt-dlp wrote into the thumbnails directory. Videos without a
+// thumbnail get no cover.
+func (y *YTDLPBackend) finishCover(ctx context.Context, req DownloadRequest, path, thumbnails string) error {
+	cover := req.Cover
+	if cover == "" && thumbnails != "" {
+		thumbnail := filepath.Join(thumbnails, "thumbnail.jpg")
+		if _, err := os.Stat(thumbnail); err != nil {
+			return nil
+		}
+		cover = filepath.Join(thumbnails, "cover.jpg")
+		if err := coverart.Process(thumbnail, cover, req.CoverArt); err != nil {
+			return fmt.Errorf("processing cover: %w", err)
+		}
+	}
+	if cover == "" {
+		return nil
+	}
+
+	if req.embedsThumbnail() {
+		if err := y.embedCover(ctx, path, cover); err != nil {
+			return err
+		}
+	}
+	if req.writesCoverFile() {
+		return saveCoverFile(cover, filepath.Join(filepath.Dir(path), req.CoverFile))
+	}
+	return nil
+}
+
+// saveCoverFile copies cover to path unless a cover is already there, e.g.
+// written by another track of the same album
+func saveCoverFile(cover, path string) error {
+	data, err := os.ReadFile(cover)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("saving cover: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("saving cover: %w", err)
+	}
+	return f.Close()
+}
+
+// embedCover makes the JPEG image at cover the front cover of the audio
+// file at path. Ogg files get it as a picture comment, which ffmpeg can't
+// write; other files are replaced once ffmpeg succeeds.
+func (y *YTDLPBackend) embedCover(ctx context.Context, path, cover string) error {
+	ext := filepath.Ext(path)
+	switch strings.ToLower(ext) {
+	case ".opus", ".ogg":
+		return coverart.EmbedOgg(path, cover)
+	case ".mp3", ".m4a", ".flac":
+	default:
+		return fmt.Errorf("embedding cover: %s files can't hold cover art", ext)
+	}
+
+	tmp := strings.TrimSuffix(path, ext) + ".cover" + ext
+	args := []string{
+		"-i", path, "-i", cover,
+		"-map", "0:a", "-map", "1:0", "-c", "copy",
+		"-disposition:v:0", "attached_pic",
+		"-metadata:s:v", "title=Album cover",
+		"-metadata:s:v", "comment=Cover (front)",
+	}
+	if strings.EqualFold(ext, ".mp3") {
+		args = append(args, "-id3v2_version", "3")
+	}
+	if err := y.ffmpeg(ctx, append(args, tmp)...); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("embedding cover: %w", err)
+	}
+	return nil
+}
+
+// ffmpeg runs ffmpeg like command runs yt-dlp, returning its last error
+// line when it fails
+func (y *YTDLPBackend) ffmpeg(ctx context.Context, args ...string) error {
+	binary := y.FFmpeg
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	cmd := exec.CommandContext(ctx, binary, append([]string{"-y", "-v", "error"}, args...)...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := startProcess(cmd)
+	if err == nil {
+		err = waitProcess(cmd)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		if lines := strings.Split(strings.TrimSpace(stderr.String()), "\n"); lines[len(lines)-1] != "" {
+			return fmt.Errorf("%s", lines[len(lines)-1])
+		}
+		return err
+	}
+	return nil
+}
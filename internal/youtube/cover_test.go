@@ -0,0 +1,38 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbedCoverFormats(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.jpg")
+	os.WriteFile(cover, []byte("not checked before the format"), 0o644)
+	y := &YTDLPBackend{FFmpeg: filepath.Join(dir, "missing-ffmpeg")}
+
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{"song.wav", ".wav files can't hold cover art"},
+		{"song.webm", ".webm files can't hold cover art"},
+		// Ogg files are handled without ffmpeg
+		{"song.opus", "embedding cover"},
+		{"song.OGG", "embedding cover"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		os.WriteFile(path, []byte("audio"), 0o644)
+		err := y.embedCover(context.Background(), path, cover)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) || strings.Contains(err.Error(), "missing-ffmpeg") {
+			t.Errorf("embedCover(%s) = %v, want an error containing %q", tt.file, err, tt.wantErr)
+		}
+		if got, _ := os.ReadFile(path); string(got) != "audio" {
+			t.Errorf("embedCover(%s) changed the file", tt.file)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package youtube
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrorCategory classifies why a download failed
+type ErrorCategory string
+
+const (
+	CategoryPrivate       ErrorCategory = "private"
+	CategoryRemoved       ErrorCategory = "removed"
+	CategoryGeoBlocked    ErrorCategory = "geo-blocked"
+	CategoryAgeRestricted ErrorCategory = "age-restricted"
+	CategoryCopyright     ErrorCategory = "copyright"
+	CategoryRateLimited   ErrorCategory = "rate-limited"
+	CategoryNetwork       ErrorCategory = "network"
+	CategoryOther         ErrorCategory = "other"
+)
+
+// ErrorCategories lists every category in the order summaries show them
+var ErrorCategories = []ErrorCategory{
+	CategoryPrivate, CategoryRemoved, CategoryGeoBlocked, CategoryAgeRestricted,
+	CategoryCopyright, CategoryRateLimited, CategoryNetwork, CategoryOther,
+}
+
+// Errors matching a DownloadError of each category with errors.Is, e.g.
+// errors.Is(err, ErrPrivate)
+var (
+	ErrPrivate       = errors.New("video is private")
+	ErrRemoved       = errors.New("video was removed")
+	ErrGeoBlocked    = errors.New("video is blocked in this country")
+	ErrAgeRestricted = errors.New("video is age-restricted")
+	ErrCopyright     = errors.New("video is blocked on copyright grounds")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrNetwork       = errors.New("network error")
+)
+
+// categoryErrors maps categories to their errors; CategoryOther has none
+var categoryErrors = map[ErrorCategory]error{
+	CategoryPrivate:       ErrPrivate,
+	CategoryRemoved:       ErrRemoved,
+	CategoryGeoBlocked:    ErrGeoBlocked,
+	CategoryAgeRestricted: ErrAgeRestricted,
+	CategoryCopyright:     ErrCopyright,
+	CategoryRateLimited:   ErrRateLimited,
+	CategoryNetwork:       ErrNetwork,
+}
+
+// Transient reports whether retrying later might succeed
+func (c ErrorCategory) Transient() bool {
+	return c == CategoryRateLimited || c == CategoryNetwork
+}
+
+// Label returns a human readable name for the category
+func (c ErrorCategory) Label() string {
+	switch c {
+	case CategoryPrivate:
+		return "Private videos"
+	case CategoryRemoved:
+		return "Removed or unavailable"
+	case CategoryGeoBlocked:
+		return "Blocked in your country"
+	case CategoryAgeRestricted:
+		return "Age-restricted"
+	case CategoryCopyright:
+		return "Blocked on copyright grounds"
+	case CategoryRateLimited:
+		return "Rate limited by YouTube"
+	case CategoryNetwork:
+		return "Network errors"
+	}
+	return "Other errors"
+}
+
+// DownloadError is a failed download with yt-dlp's explanation of why
+type DownloadError struct {
+	Category ErrorCategory
+	// Message is yt-dlp's error message, without the "ERROR: [youtube] id:" prefix
+	Message string
+	// Err is the underlying failure, usually the process exit status
+	Err error
+}
+
+func (e *DownloadError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return string(e.Category)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the error of e's category
+func (e *DownloadError) Is(target error) bool {
+	err, ok := categoryErrors[e.Category]
+	return ok && err == target
+}
+
+// CategoryOf returns the category of err, CategoryOther when it isn't a
+// DownloadError
+func CategoryOf(err error) ErrorCategory {
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.Category
+	}
+	return CategoryOther
+}
+
+// Warning is returned together with the path of a download that succeeded
+// but whose finishing touches, such as recording it in the archive, failed.
+// The file is kept and counts as downloaded.
+type Warning struct {
+	Err error
+}
+
+func (w *Warning) Error() string {
+	return w.Err.Error()
+}
+
+func (w *Warning) Unwrap() error {
+	return w.Err
+}
+
+// IsWarning reports whether err only warns about a download that succeeded
+func IsWarning(err error) bool {
+	var w *Warning
+	return errors.As(err, &w)
+}
+
+// Warn adds problem to err, which is nil or a *Warning returned by a
+// successful download, and returns the combined Warning
+func Warn(err, problem error) error {
+	if problem == nil {
+		return err
+	}
+	var w *Warning
+	if errors.As(err, &w) {
+		return &Warning{Err: errors.Join(w.Err, problem)}
+	}
+	return &Warning{Err: problem}
+}
+
+// errorPatterns map yt-dlp error messages to categories. Permanent
+// failures come first because their messages sometimes mention HTTP errors.
+// Only connection failures, timeouts and HTTP 5xx count as network errors;
+// other HTTP errors such as 403 won't go away by retrying.
+var errorPatterns = []struct {
+	category ErrorCategory
+	pattern  *regexp.Regexp
+}{
+	{CategoryPrivate, regexp.MustCompile(`(?i)private video|video is private`)},
+	{CategoryCopyright, regexp.MustCompile(`(?i)copyright`)},
+	{CategoryGeoBlocked, regexp.MustCompile(`(?i)not (made this video )?available in your country|geo[- ]?restrict`)},
+	{CategoryAgeRestricted, regexp.MustCompile(`(?i)confirm your age|age[- ]restricted|inappropriate for some users`)},
+	{CategoryRemoved, regexp.MustCompile(`(?i)video unavailable|has been removed|no longer available|account .* terminated|does not exist`)},
+	{CategoryRateLimited, regexp.MustCompile(`(?i)HTTP Error 429|too many requests`)},
+	{CategoryNetwork, regexp.MustCompile(`(?i)HTTP Error 5\d\d|connection (reset|refused|aborted)|timed out|` +
+		`remote end closed|incompleteread|temporary failure in name resolution|name or service not known|` +
+		`network is unreachable|giving up after \d+ (fragment )?retries`)},
+}
+
+// errorPrefix matches the "ERROR: [extractor] id: " start of yt-dlp errors
+var errorPrefix = regexp.MustCompile(`^ERROR:\s*(\[[^\]]+\]\s*)?([\w-]+:\s+)?`)
+
+// classifyError turns a failed yt-dlp run into a DownloadError using the
+// last ERROR line it printed to stderr
+func classifyError(stderr string, err error) *DownloadError {
+	var message string
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "ERROR:") {
+			message = errorPrefix.ReplaceAllString(line, "")
+		}
+	}
+
+	de := &DownloadError{Category: CategoryOther, Message: message, Err: err}
+	for _, p := range errorPatterns {
+		if p.pattern.MatchString(message) {
+			de.Category = p.category
+			break
+		}
+	}
+	return de
+}
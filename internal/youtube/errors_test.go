@@ -0,0 +1,103 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		stderr   string
+		category ErrorCategory
+		message  string
+	}{
+		{"ERROR: [youtube] abc123def45: Private video. Sign in if you've been granted access to this video",
+			CategoryPrivate, "Private video. Sign in if you've been granted access to this video"},
+		{"ERROR: [youtube] abc123def45: Video unavailable. This video has been removed by the uploader",
+			CategoryRemoved, "Video unavailable. This video has been removed by the uploader"},
+		{"ERROR: [youtube] abc123def45: Video unavailable. This video contains content from UMG, who has blocked it on copyright grounds",
+			CategoryCopyright, "Video unavailable. This video contains content from UMG, who has blocked it on copyright grounds"},
+		{"ERROR: [youtube] abc123def45: The uploader has not made this video available in your country",
+			CategoryGeoBlocked, "The uploader has not made this video available in your country"},
+		{"ERROR: [youtube] abc123def45: Sign in to confirm your age. This video may be inappropriate for some users.",
+			CategoryAgeRestricted, "Sign in to confirm your age. This video may be inappropriate for some users."},
+		{"ERROR: [youtube] abc123def45: Unable to download webpage: HTTP Error 429: Too Many Requests",
+			CategoryRateLimited, "Unable to download webpage: HTTP Error 429: Too Many Requests"},
+		{"ERROR: unable to download video data: HTTP Error 503: Service Unavailable",
+			CategoryNetwork, "unable to download video data: HTTP Error 503: Service Unavailable"},
+		{"ERROR: [youtube] abc123def45: Unable to download API page: <urlopen error [Errno 104] Connection reset by peer>",
+			CategoryNetwork, "Unable to download API page: <urlopen error [Errno 104] Connection reset by peer>"},
+		{"ERROR: [download] Got error: The read operation timed out",
+			CategoryNetwork, "Got error: The read operation timed out"},
+		{"ERROR: Giving up after 10 fragment retries",
+			CategoryNetwork, "Giving up after 10 fragment retries"},
+		// Retrying won't fix a forbidden download, even though the message
+		// looks like a network failure
+		{"ERROR: unable to download video data: HTTP Error 403: Forbidden",
+			CategoryOther, "unable to download video data: HTTP Error 403: Forbidden"},
+		{"ERROR: Postprocessing: audio conversion failed: Error opening output files",
+			CategoryOther, "audio conversion failed: Error opening output files"},
+		{"WARNING: something odd\nERROR: first\nERROR: [youtube] abc123def45: Private video",
+			CategoryPrivate, "Private video"},
+		{"", CategoryOther, ""},
+	}
+	exit := errors.New("exit status 1")
+	for _, tt := range tests {
+		de := classifyError(tt.stderr, exit)
+		if de.Category != tt.category || de.Message != tt.message {
+			t.Errorf("classifyError(%q) = %s %q, want %s %q", tt.stderr, de.Category, de.Message, tt.category, tt.message)
+		}
+		if !errors.Is(de, exit) {
+			t.Errorf("classifyError(%q) doesn't wrap the exit error", tt.stderr)
+		}
+	}
+}
+
+func TestDownloadErrorIs(t *testing.T) {
+	err := error(&DownloadError{Category: CategoryPrivate, Message: "Private video"})
+	if !errors.Is(err, ErrPrivate) {
+		t.Error("private DownloadError doesn't match ErrPrivate")
+	}
+	if errors.Is(err, ErrRemoved) {
+		t.Error("private DownloadError matches ErrRemoved")
+	}
+	if errors.Is(&DownloadError{Category: CategoryOther}, ErrNetwork) {
+		t.Error("other DownloadError matches ErrNetwork")
+	}
+	for _, category := range ErrorCategories {
+		if want := category == CategoryRateLimited || category == CategoryNetwork; category.Transient() != want {
+			t.Errorf("%s.Transient() = %v, want %v", category, !want, want)
+		}
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	wrapped := fmt.Errorf("download failed: %w", &DownloadError{Category: CategoryGeoBlocked})
+	if got := CategoryOf(wrapped); got != CategoryGeoBlocked {
+		t.Errorf("CategoryOf(wrapped) = %s, want %s", got, CategoryGeoBlocked)
+	}
+	if got := CategoryOf(errors.New("boom")); got != CategoryOther {
+		t.Errorf("CategoryOf(plain) = %s, want %s", got, CategoryOther)
+	}
+}
+
+func TestWarn(t *testing.T) {
+	if err := Warn(nil, nil); err != nil {
+		t.Errorf("Warn(nil, nil) = %v, want nil", err)
+	}
+
+	cover := errors.New("cover failed")
+	lyrics := errors.New("lyrics failed")
+	err := Warn(nil, cover)
+	if !IsWarning(err) || !errors.Is(err, cover) {
+		t.Fatalf("Warn(nil, cover) = %v, want a warning wrapping it", err)
+	}
+	err = Warn(err, lyrics)
+	if !IsWarning(err) || !errors.Is(err, cover) || !errors.Is(err, lyrics) {
+		t.Errorf("Warn(warning, lyrics) = %v, want a warning wrapping both", err)
+	}
+	if IsWarning(errors.New("failed")) || IsWarning(nil) {
+		t.Error("IsWarning reports plain errors as warnings")
+	}
+}
@@ -0,0 +1,181 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FakeBackend is a scripted in-memory Backend for exercising the TUI offline.
+// Responses are looked up in the maps below; anything missing behaves like a
+// video or playlist that does not exist.
+type FakeBackend struct {
+	// SearchResults maps a query to the results returned for it
+	SearchResults map[string][]SearchResult
+	// Videos maps a video ID to its metadata
+	Videos map[string]VideoMetadata
+	// Playlists maps a playlist ID to the playlist
+	Playlists map[string]Playlist
+	// DownloadErrors makes downloads of the given video IDs fail, or
+	// succeed with a warning when the error is a *Warning
+	DownloadErrors map[string]error
+	// TransientFailures makes the first N downloads of the given video IDs
+	// fail with a network error, to exercise retries
+	TransientFailures map[string]int
+	// Delay is applied to every call to simulate network latency
+	Delay time.Duration
+
+	mu         sync.Mutex
+	downloads  []DownloadRequest
+	thumbnails []string
+}
+
+// NewFakeBackend creates an empty fake backend
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		SearchResults:     map[string][]SearchResult{},
+		Videos:            map[string]VideoMetadata{},
+		Playlists:         map[string]Playlist{},
+		DownloadErrors:    map[string]error{},
+		TransientFailures: map[string]int{},
+	}
+}
+
+// AddVideo registers a video and returns it as a search result
+func (f *FakeBackend) AddVideo(metadata VideoMetadata) SearchResult {
+	f.Videos[metadata.ID] = metadata
+	return SearchResult{Title: metadata.Title, ID: metadata.ID, Channel: metadata.Channel}
+}
+
+// Search returns the scripted results for query, truncated to limit
+func (f *FakeBackend) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	results := f.SearchResults[query]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Metadata returns the scripted metadata for videoID
+func (f *FakeBackend) Metadata(ctx context.Context, videoID string) (*VideoMetadata, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	metadata, ok := f.Videos[videoID]
+	if !ok {
+		return nil, notFound("video", videoID)
+	}
+	return &metadata, nil
+}
+
+// Download records the request, reports a few progress steps and fails (or
+// warns) if an error was scripted for it. Nothing is written; the returned
+// path is where the file would have been saved.
+func (f *FakeBackend) Download(ctx context.Context, req DownloadRequest, progress ProgressFunc) (string, error) {
+	const size = 4 << 20
+	for _, pct := range []int64{0, 25, 50, 75, 100} {
+		if err := f.wait(ctx); err != nil {
+			return "", err
+		}
+		if progress != nil {
+			progress(Progress{
+				Stage:      StageDownloading,
+				Percent:    float64(pct),
+				Downloaded: size * pct / 100,
+				Total:      size,
+				ETA:        -1,
+			})
+		}
+	}
+	if progress != nil {
+		progress(Progress{Stage: StageConverting, Percent: 100, ETA: -1})
+	}
+
+	f.mu.Lock()
+	f.downloads = append(f.downloads, req)
+	transient := f.TransientFailures[req.VideoID] > 0
+	if transient {
+		f.TransientFailures[req.VideoID]--
+	}
+	f.mu.Unlock()
+	if transient {
+		return "", &DownloadError{Category: CategoryNetwork, Message: "Connection reset by peer"}
+	}
+	err := f.DownloadErrors[req.VideoID]
+	if err != nil && !IsWarning(err) {
+		return "", err
+	}
+
+	if metadata, ok := f.Videos[req.VideoID]; ok && req.Title == "" {
+		req.Title, req.Channel = metadata.Title, metadata.Channel
+	}
+	name := expandTemplate(req.template(), func(token string) string {
+		return sanitizeFilename(req.knownValue(token))
+	})
+	ext := req.extension()
+	if req.quality() == QualityOriginal {
+		ext = "opus" // what YouTube usually serves
+	}
+	return filepath.Join(req.outputDir(), name) + "." + ext, err
+}
+
+// Playlist returns the scripted playlist for playlistID
+func (f *FakeBackend) Playlist(ctx context.Context, playlistID string) (*Playlist, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	playlist, ok := f.Playlists[playlistID]
+	if !ok {
+		return nil, notFound("playlist", playlistID)
+	}
+	return &playlist, nil
+}
+
+// Thumbnail records the request and reports success. Nothing is written.
+func (f *FakeBackend) Thumbnail(ctx context.Context, videoID, path string) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+	if _, ok := f.Videos[videoID]; !ok {
+		return notFound("video", videoID)
+	}
+	f.mu.Lock()
+	f.thumbnails = append(f.thumbnails, videoID)
+	f.mu.Unlock()
+	return nil
+}
+
+// Thumbnails returns the IDs of every video whose thumbnail was saved
+func (f *FakeBackend) Thumbnails() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.thumbnails...)
+}
+
+// Downloads returns every download request received so far
+func (f *FakeBackend) Downloads() []DownloadRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]DownloadRequest(nil), f.downloads...)
+}
+
+// notFound is the error returned for an unscripted video or playlist
+func notFound(kind, id string) error {
+	return &DownloadError{Category: CategoryRemoved, Message: fmt.Sprintf("%s %s does not exist", kind, id)}
+}
+
+// wait sleeps for Delay, returning early with ctx's error when cancelled
+func (f *FakeBackend) wait(ctx context.Context) error {
+	if f.Delay > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(f.Delay):
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,202 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AudioFormat is the codec/container downloads are converted to
+type AudioFormat string
+
+const (
+	FormatMP3    AudioFormat = "mp3"
+	FormatOpus   AudioFormat = "opus"
+	FormatM4A    AudioFormat = "m4a"
+	FormatFLAC   AudioFormat = "flac"
+	FormatVorbis AudioFormat = "vorbis"
+	FormatWAV    AudioFormat = "wav"
+)
+
+// AudioFormats lists the supported formats in the order the UI cycles them
+var AudioFormats = []AudioFormat{FormatMP3, FormatOpus, FormatM4A, FormatFLAC, FormatVorbis, FormatWAV}
+
+// Quality is an encoding preset applied when converting audio
+type Quality string
+
+const (
+	QualityV0       Quality = "v0"
+	QualityV2       Quality = "v2"
+	QualityCBR320   Quality = "320k"
+	QualityCBR192   Quality = "192k"
+	QualityOriginal Quality = "original"
+)
+
+// Qualities lists the supported presets in the order the UI cycles them
+var Qualities = []Quality{QualityV0, QualityV2, QualityCBR320, QualityCBR192, QualityOriginal}
+
+// Default format settings
+const (
+	DefaultFormat  = FormatMP3
+	DefaultQuality = QualityV0
+)
+
+// ParseAudioFormat parses a format name such as "mp3" or "aac"
+func ParseAudioFormat(s string) (AudioFormat, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "aac":
+		return FormatM4A, nil
+	case "ogg":
+		return FormatVorbis, nil
+	}
+	for _, f := range AudioFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown audio format %q (valid: mp3, opus, m4a, aac, flac, vorbis, ogg, wav)", s)
+}
+
+// ParseQuality parses a quality preset name such as "v0" or "320k"
+func ParseQuality(s string) (Quality, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, q := range Qualities {
+		if string(q) == s {
+			return q, nil
+		}
+	}
+	return "", fmt.Errorf("unknown quality %q (valid: v0, v2, 320k, 192k, original)", s)
+}
+
+// Extension returns the file extension written for the format
+func (f AudioFormat) Extension() string {
+	if f == FormatVorbis {
+		return "ogg"
+	}
+	return string(f)
+}
+
+// Label returns a human readable name for the format
+func (f AudioFormat) Label() string {
+	switch f {
+	case FormatM4A:
+		return "M4A (AAC)"
+	case FormatVorbis:
+		return "OGG Vorbis"
+	}
+	return strings.ToUpper(string(f))
+}
+
+// Lossless reports whether the format ignores quality presets
+func (f AudioFormat) Lossless() bool {
+	return f == FormatFLAC || f == FormatWAV
+}
+
+// Label returns a human readable name for the preset
+func (q Quality) Label() string {
+	switch q {
+	case QualityV0:
+		return "VBR V0 (best)"
+	case QualityV2:
+		return "VBR V2"
+	case QualityCBR320:
+		return "CBR 320 kbps"
+	case QualityCBR192:
+		return "CBR 192 kbps"
+	case QualityOriginal:
+		return "Original codec (no re-encode)"
+	}
+	return string(q)
+}
+
+// Next returns the format after f, wrapping around
+func (f AudioFormat) Next() AudioFormat {
+	for i, format := range AudioFormats {
+		if format == f {
+			return AudioFormats[(i+1)%len(AudioFormats)]
+		}
+	}
+	return DefaultFormat
+}
+
+// Next returns the preset after q, wrapping around
+func (q Quality) Next() Quality {
+	for i, quality := range Qualities {
+		if quality == q {
+			return Qualities[(i+1)%len(Qualities)]
+		}
+	}
+	return DefaultQuality
+}
+
+// FormatLabel describes the format and quality of o for display
+func (o DownloadOptions) FormatLabel() string {
+	if o.quality() == QualityOriginal {
+		return QualityOriginal.Label()
+	}
+	if o.format().Lossless() {
+		return o.format().Label() + " (lossless)"
+	}
+	return o.format().Label() + " • " + o.quality().Label()
+}
+
+// FormatName returns the short name of the output format, or "original"
+// when the source codec is kept
+func (o DownloadOptions) FormatName() string {
+	if o.quality() == QualityOriginal {
+		return string(QualityOriginal)
+	}
+	return string(o.format())
+}
+
+// extension returns the file extension downloads with o will have, or
+// "{ext}" when the original codec is kept
+func (o DownloadOptions) extension() string {
+	if o.quality() == QualityOriginal {
+		return "{ext}"
+	}
+	return o.format().Extension()
+}
+
+func (o DownloadOptions) format() AudioFormat {
+	if o.Format == "" {
+		return DefaultFormat
+	}
+	return o.Format
+}
+
+func (o DownloadOptions) quality() Quality {
+	if o.Quality == "" {
+		return DefaultQuality
+	}
+	return o.Quality
+}
+
+// ytdlpAudioArgs returns the yt-dlp arguments that extract and convert audio
+func (o DownloadOptions) ytdlpAudioArgs() []string {
+	args := []string{"-f", "bestaudio", "--extract-audio"}
+
+	if o.quality() == QualityOriginal {
+		// "best" keeps the source codec and only remuxes
+		return append(args, "--audio-format", "best")
+	}
+
+	args = append(args, "--audio-format", string(o.format()))
+	switch o.quality() {
+	case QualityV0:
+		args = append(args, "--audio-quality", "0")
+	case QualityV2:
+		args = append(args, "--audio-quality", "2")
+	case QualityCBR320:
+		args = append(args, "--audio-quality", "320K")
+	case QualityCBR192:
+		args = append(args, "--audio-quality", "192K")
+	}
+	return args
+}
+
+// embedsThumbnail reports whether cover art can be embedded in the output
+func (o DownloadOptions) embedsThumbnail() bool {
+	// WAV has no standard way to carry cover art
+	return o.quality() == QualityOriginal || o.format() != FormatWAV
+}
@@ -0,0 +1,94 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adelapazborrero/music_download/internal/lyrics"
+)
+
+// LyricsLabel describes the lyrics setting, e.g. "from en subtitles"
+func (o DownloadOptions) LyricsLabel() string {
+	if !o.Lyrics.Enabled {
+		return "off"
+	}
+	return fmt.Sprintf("from %s subtitles", o.Lyrics.Lang())
+}
+
+// ytdlpSubtitleArgs makes yt-dlp save the subtitles in the preferred
+// language into dir without downloading the video: manual ones when the
+// video has them, else the "-orig" auto-caption track, YouTube's speech
+// recognition in the video's own language. The plain language's
+// auto-captions may be machine-translated, so they aren't used.
+func (req DownloadRequest) ytdlpSubtitleArgs(dir string) []string {
+	lang := req.Lyrics.Lang()
+	return []string{
+		"--skip-download",
+		"--write-subs",
+		"--write-auto-subs",
+		"--sub-langs", lang + "," + lang + "-orig",
+		"--sub-format", "json3",
+		"--quiet",
+		"--no-warnings",
+		"-o", "subtitle:" + ytdlpEscape(filepath.Join(dir, "subtitle")) + ".%(ext)s",
+	}
+}
+
+// fetchLyrics fetches the video's subtitles in a run of its own after the
+// download, so subtitles that can't be fetched (e.g. HTTP 429) never fail
+// the song, and saves them with finishLyrics
+func (y *YTDLPBackend) fetchLyrics(ctx context.Context, req DownloadRequest, path string) error {
+	dir, err := os.MkdirTemp("", "music-download-subtitles-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := y.output(ctx, append(req.ytdlpSubtitleArgs(dir), VideoURL(req.VideoID))...); err != nil {
+		return fmt.Errorf("fetching subtitles: %w", err)
+	}
+	return finishLyrics(req, path, dir)
+}
+
+// finishLyrics saves the subtitles yt-dlp wrote into the subtitles
+// directory as an LRC file next to the download at path, and embeds them in
+// MP3 files. Videos without usable subtitles get no lyrics.
+func finishLyrics(req DownloadRequest, path, subtitles string) error {
+	l := pickSubtitles(subtitles, req.Lyrics)
+	if l == nil {
+		return nil
+	}
+
+	tags := req.WrittenTags()
+	lrc := strings.TrimSuffix(path, filepath.Ext(path)) + ".lrc"
+	if err := os.WriteFile(lrc, l.LRC(artistName(tags.Artist), tags.Title, tags.Album), 0o644); err != nil {
+		return fmt.Errorf("saving lyrics: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".mp3") {
+		return lyrics.Embed(path, l)
+	}
+	return nil
+}
+
+// pickSubtitles returns the best usable subtitles in dir: manual ones in
+// the preferred language, then its untranslated "-orig" auto-captions. Auto
+// captions filed under the plain language are machine translations and are
+// skipped.
+func pickSubtitles(dir string, opts lyrics.Options) *lyrics.Lyrics {
+	lang := opts.Lang()
+	for _, track := range []string{lang, lang + "-orig"} {
+		data, err := os.ReadFile(filepath.Join(dir, "subtitle."+track+".json3"))
+		if err != nil {
+			continue
+		}
+		l, err := lyrics.ParseJSON3(data, lang)
+		if err != nil || (track == lang && l.Auto) || !opts.Accepts(l) {
+			continue
+		}
+		return l
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package youtube
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/adelapazborrero/music_download/internal/lyrics"
+)
+
+// Subtitle tracks in json3: manual subtitles have no word offsets, YouTube's
+// auto-captions time every word
+const (
+	manualJSON3 = `{"events":[{"tStartMs":1000,"segs":[{"utf8":"manual line"}]}]}`
+	autoJSON3   = `{"events":[{"tStartMs":1000,"segs":[{"utf8":"auto","acAsrConf":250},{"utf8":" line","tOffsetMs":300,"acAsrConf":250}]}]}`
+)
+
+func TestPickSubtitles(t *testing.T) {
+	tests := []struct {
+		name   string
+		tracks map[string]string
+		want   string
+	}{
+		{"manual subtitles", map[string]string{"en": manualJSON3, "en-orig": autoJSON3}, "manual line"},
+		{"original auto-captions", map[string]string{"en-orig": autoJSON3}, "auto line"},
+		{"translated auto-captions are skipped", map[string]string{"en": autoJSON3}, ""},
+		{"original beats translated", map[string]string{"en": autoJSON3, "en-orig": autoJSON3}, "auto line"},
+		{"other languages are ignored", map[string]string{"de": manualJSON3}, ""},
+		{"no subtitles", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for lang, data := range tt.tracks {
+				if err := os.WriteFile(filepath.Join(dir, "subtitle."+lang+".json3"), []byte(data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			l := pickSubtitles(dir, lyrics.Options{Enabled: true, MinQuality: 60})
+			var got string
+			if l != nil {
+				got = l.Text()
+				if l.Language != "en" {
+					t.Errorf("picked language %q, want en", l.Language)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("pickSubtitles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYTDLPSubtitleArgs(t *testing.T) {
+	req := DownloadRequest{DownloadOptions: DownloadOptions{Lyrics: lyrics.Options{Enabled: true, Language: "es"}}}
+	args := req.ytdlpSubtitleArgs("/tmp/subs")
+	i := slices.Index(args, "--sub-langs")
+	if i < 0 || args[i+1] != "es,es-orig" {
+		t.Errorf("ytdlpSubtitleArgs() = %q, want --sub-langs es,es-orig", args)
+	}
+	if !slices.Contains(args, "--skip-download") {
+		t.Errorf("ytdlpSubtitleArgs() = %q, want a subtitle-only run", args)
+	}
+}
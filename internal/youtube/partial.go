@@ -0,0 +1,62 @@
+package youtube
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partialFiles tracks what a cancelled yt-dlp download may leave behind
+type partialFiles struct {
+	// filename is the file being downloaded, before audio extraction
+	filename string
+	// created lists output files that didn't exist when the download
+	// started, so removing them never deletes an earlier download
+	created []string
+}
+
+// newPartialFiles tracks a download into filename that is converted to
+// ext ("{ext}" when the source codec is kept). It must be called before
+// the download starts writing.
+func newPartialFiles(filename, ext string) *partialFiles {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	candidates := []string{filename, base + ".webp", base + ".jpg", base + ".png"}
+	if !strings.Contains(ext, "{") {
+		candidates = append(candidates, base+"."+ext)
+	}
+
+	p := &partialFiles{filename: filename}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			p.created = append(p.created, path)
+		}
+	}
+	return p
+}
+
+// remove deletes the partial download, yt-dlp's fragment and resume
+// files, the thumbnail being embedded and a half-converted audio file
+func (p *partialFiles) remove() {
+	if p == nil {
+		return
+	}
+	paths := append([]string{p.filename + ".part", p.filename + ".ytdl"}, p.created...)
+	fragments, _ := filepath.Glob(globEscape(p.filename) + ".part-Frag*")
+	paths = append(paths, fragments...)
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// globEscape quotes the glob metacharacters in path
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,443 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/adelapazborrero/music_download/internal/coverart"
+)
+
+// DefaultPlaylistWorkers is the number of playlist items downloaded at once
+const DefaultPlaylistWorkers = 3
+
+// PlaylistItemStartedMsg is sent when a worker starts downloading an item
+type PlaylistItemStartedMsg struct {
+	Download *PlaylistDownload
+	Index    int
+	Title    string
+}
+
+// PlaylistItemProgressMsg reports the progress of an in-flight item
+type PlaylistItemProgressMsg struct {
+	Download *PlaylistDownload
+	Index    int
+	Progress Progress
+}
+
+// PlaylistDownloadProgressMsg is sent each time an item finishes or is
+// skipped. Current counts finished items and always increases by one per
+// message.
+type PlaylistDownloadProgressMsg struct {
+	Download *PlaylistDownload
+	Current  int
+	Total    int
+	Index    int
+	ID       string
+	Title    string
+	Path     string
+	Success  bool
+	Skipped  bool
+	// SkipReason says why a skipped item wasn't downloaded
+	SkipReason string
+	// Warning describes a non-fatal problem with a successful item
+	Warning string
+	Error   string
+	// Category classifies the failure when Error is set
+	Category ErrorCategory
+}
+
+// Reasons an item is skipped
+const (
+	SkipArchived = "already downloaded"
+	SkipByUser   = "skipped"
+)
+
+type PlaylistDownloadCompleteMsg struct {
+	Download    *PlaylistDownload
+	Success     int
+	Failed      int
+	Skipped     int
+	FailedItems []string
+	// FailedByCategory groups the FailedItems entries by why they failed
+	FailedByCategory map[ErrorCategory][]string
+	// Cancelled is set when the download was stopped with Cancel; items
+	// that were in flight or not started yet aren't reported
+	Cancelled bool
+	// Aborted is set when Abort stopped the download early; items that
+	// weren't started yet aren't reported
+	Aborted bool
+	Err     error
+}
+
+// PlaylistJob describes a set of items downloaded through the worker pool
+type PlaylistJob struct {
+	// Name fills the {playlist} filename token
+	Name    string
+	Items   []SearchResult
+	Options DownloadOptions
+	// Workers is the number of items downloaded in parallel
+	Workers int
+	// Skip, when set, reports video IDs that should not be downloaded,
+	// e.g. because they are already in the download archive
+	Skip func(videoID string) bool
+	// Indexes, when set, limits the download to these positions in Items,
+	// e.g. the unfinished items of a resumed job. The other items count
+	// as already finished.
+	Indexes []int
+	// Album, when set, tags the items as the tracks of one album, saved
+	// into one folder with a shared cover
+	Album *Album
+}
+
+// PlaylistDownload runs playlist items through a pool of download workers
+// and streams their progress into the bubbletea program
+type PlaylistDownload struct {
+	job     PlaylistJob
+	items   []SearchResult
+	updates chan tea.Msg
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// cover is the image shared by the tracks of an album
+	cover string
+
+	// mu guards the dispatch state below; workers wait on resumed while
+	// the download is paused
+	mu       sync.Mutex
+	resumed  *sync.Cond
+	indexes  []int
+	next     int
+	paused   bool
+	aborted  bool
+	inFlight map[int]context.CancelFunc
+	skipped  map[int]bool
+}
+
+// StartPlaylistDownload starts downloading the job's items in the
+// background. Progress arrives as PlaylistItemStartedMsg,
+// PlaylistItemProgressMsg and PlaylistDownloadProgressMsg, followed by a
+// final PlaylistDownloadCompleteMsg; read them with Next. Cancelling ctx
+// stops the download like Cancel.
+func StartPlaylistDownload(ctx context.Context, b Backend, job PlaylistJob) *PlaylistDownload {
+	if job.Workers < 1 {
+		job.Workers = DefaultPlaylistWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p := &PlaylistDownload{
+		job: job,
+		// Copied because workers fill in resolved query items
+		items:    append([]SearchResult(nil), job.Items...),
+		updates:  make(chan tea.Msg, job.Workers),
+		ctx:      ctx,
+		cancel:   cancel,
+		indexes:  job.Indexes,
+		inFlight: map[int]context.CancelFunc{},
+		skipped:  map[int]bool{},
+	}
+	p.resumed = sync.NewCond(&p.mu)
+	if p.indexes == nil {
+		p.indexes = make([]int, len(p.items))
+		for i := range p.indexes {
+			p.indexes[i] = i
+		}
+	}
+	// Wake paused workers so they notice the cancellation
+	context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.resumed.Broadcast()
+		p.mu.Unlock()
+	})
+	go p.run(b)
+	return p
+}
+
+// Cancel stops the download, killing the items in flight. The stream
+// still ends with a PlaylistDownloadCompleteMsg, with Cancelled set.
+func (p *PlaylistDownload) Cancel() {
+	p.cancel()
+}
+
+// Pause stops starting new items. Items in flight finish normally.
+func (p *PlaylistDownload) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+}
+
+// Resume continues a paused download
+func (p *PlaylistDownload) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.resumed.Broadcast()
+	p.mu.Unlock()
+}
+
+// Paused reports whether the download is paused
+func (p *PlaylistDownload) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Abort lets the items in flight finish but starts no more. The stream
+// ends with a PlaylistDownloadCompleteMsg, with Aborted set.
+func (p *PlaylistDownload) Abort() {
+	p.mu.Lock()
+	p.aborted = true
+	p.resumed.Broadcast()
+	p.mu.Unlock()
+}
+
+// Skip stops downloading the item at index, which is then reported as
+// skipped. It returns false when the item isn't in flight.
+func (p *PlaylistDownload) Skip(index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cancel, ok := p.inFlight[index]
+	if !ok {
+		return false
+	}
+	p.skipped[index] = true
+	cancel()
+	return true
+}
+
+// Next waits for the next message of the playlist stream
+func (p *PlaylistDownload) Next() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-p.updates
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+type itemResult struct {
+	index      int
+	path       string
+	skipReason string
+	cancelled  bool
+	warning    string
+	err        error
+}
+
+// take hands the next item to a worker, waiting while the download is
+// paused. It returns false once there is nothing left to start.
+func (p *PlaylistDownload) take() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.paused && !p.aborted && p.ctx.Err() == nil {
+		p.resumed.Wait()
+	}
+	if p.aborted || p.ctx.Err() != nil || p.next >= len(p.indexes) {
+		return 0, false
+	}
+	i := p.indexes[p.next]
+	p.next++
+	return i, true
+}
+
+// track registers the item at index as in flight until the returned
+// function is called, so Skip can stop it
+func (p *PlaylistDownload) track(index int) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.mu.Lock()
+	p.inFlight[index] = cancel
+	p.mu.Unlock()
+	return ctx, func() {
+		p.mu.Lock()
+		delete(p.inFlight, index)
+		p.mu.Unlock()
+		cancel()
+	}
+}
+
+// skippedByUser reports whether Skip was called for the item at index
+func (p *PlaylistDownload) skippedByUser(index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skipped[index]
+}
+
+func (p *PlaylistDownload) run(b Backend) {
+	if p.job.Album != nil {
+		p.cover = p.fetchCover(b)
+	}
+	results := make(chan itemResult)
+
+	var wg sync.WaitGroup
+	for w := 0; w < p.job.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := p.take()
+				if !ok {
+					return
+				}
+				results <- p.downloadItem(b, i)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results are accounted here, one at a time, so counts stay consistent
+	// no matter which worker finishes first
+	var success, failed, skipped int
+	failedItems := []string{}
+	byCategory := map[ErrorCategory][]string{}
+	current := len(p.items) - len(p.indexes)
+	for r := range results {
+		if r.cancelled {
+			continue
+		}
+		current++
+		item := p.items[r.index]
+
+		var errMsg string
+		var category ErrorCategory
+		switch {
+		case r.skipReason != "":
+			skipped++
+		case r.err != nil:
+			failed++
+			errMsg = r.err.Error()
+			category = CategoryOf(r.err)
+			failure := fmt.Sprintf("%s: %s", item.Title, errMsg)
+			failedItems = append(failedItems, failure)
+			byCategory[category] = append(byCategory[category], failure)
+		default:
+			success++
+		}
+
+		p.updates <- PlaylistDownloadProgressMsg{
+			Download:   p,
+			Current:    current,
+			Total:      len(p.items),
+			Index:      r.index,
+			ID:         item.ID,
+			Title:      item.Title,
+			Path:       r.path,
+			Success:    r.err == nil && r.skipReason == "",
+			Skipped:    r.skipReason != "",
+			SkipReason: r.skipReason,
+			Warning:    r.warning,
+			Error:      errMsg,
+			Category:   category,
+		}
+	}
+
+	if p.cover != "" {
+		os.RemoveAll(filepath.Dir(p.cover))
+	}
+
+	p.mu.Lock()
+	aborted := p.aborted && p.next < len(p.indexes)
+	p.mu.Unlock()
+	p.updates <- PlaylistDownloadCompleteMsg{
+		Download:         p,
+		Cancelled:        p.ctx.Err() != nil,
+		Aborted:          aborted,
+		Success:          success,
+		Failed:           failed,
+		Skipped:          skipped,
+		FailedItems:      failedItems,
+		FailedByCategory: byCategory,
+	}
+	close(p.updates)
+	p.cancel()
+}
+
+// fetchCover makes the first track's thumbnail into cover art in a
+// temporary directory so every track of the album gets the same cover. It
+// returns the image path, or "" to keep each video's own thumbnail.
+func (p *PlaylistDownload) fetchCover(b Backend) string {
+	for _, item := range p.items {
+		if item.ID == "" {
+			continue
+		}
+		dir, err := os.MkdirTemp("", "music-download-cover-")
+		if err != nil {
+			return ""
+		}
+		thumbnail := filepath.Join(dir, "thumbnail.jpg")
+		cover := filepath.Join(dir, "cover.jpg")
+		if err := b.Thumbnail(p.ctx, item.ID, thumbnail); err != nil {
+			os.RemoveAll(dir)
+			return ""
+		}
+		if err := coverart.Process(thumbnail, cover, p.job.Options.CoverArt); err != nil {
+			os.RemoveAll(dir)
+			return ""
+		}
+		return cover
+	}
+	return ""
+}
+
+// downloadItem downloads a single item, reporting its start and progress
+func (p *PlaylistDownload) downloadItem(b Backend, index int) itemResult {
+	item := p.items[index]
+	if item.ID == "" {
+		resolved, err := resolveItem(p.ctx, b, item)
+		if err != nil && p.ctx.Err() != nil {
+			return itemResult{index: index, cancelled: true}
+		}
+		if err != nil {
+			return itemResult{index: index, err: err}
+		}
+		// Only this worker touches this index until it reports the result
+		p.items[index] = resolved
+		item = resolved
+	}
+	if p.job.Skip != nil && p.job.Skip(item.ID) {
+		return itemResult{index: index, skipReason: SkipArchived}
+	}
+	ctx, done := p.track(index)
+	defer done()
+	p.updates <- PlaylistItemStartedMsg{Download: p, Index: index, Title: item.Title}
+
+	// Batch entries given as a URL are titled with the URL until downloaded
+	title := item.Title
+	if ExtractVideoID(title) == item.ID {
+		title = ""
+	}
+	req := DownloadRequest{
+		VideoID:         item.ID,
+		Title:           title,
+		Channel:         item.Channel,
+		Playlist:        p.job.Name,
+		PlaylistIndex:   index + 1,
+		DownloadOptions: p.job.Options,
+	}
+	if p.job.Album != nil {
+		req = p.job.Album.Track(req, index+1, len(p.items))
+		req.Cover = p.cover
+	}
+	path, err := b.Download(ctx, req, func(progress Progress) {
+		// Progress updates are best-effort; drop them if the UI lags
+		select {
+		case p.updates <- PlaylistItemProgressMsg{Download: p, Index: index, Progress: progress}:
+		default:
+		}
+	})
+	// A song saved before a cancel or skip took effect is kept
+	if IsWarning(err) {
+		return itemResult{index: index, path: path, warning: err.Error()}
+	}
+	if err != nil && p.ctx.Err() != nil {
+		return itemResult{index: index, cancelled: true}
+	}
+	if err != nil && p.skippedByUser(index) {
+		return itemResult{index: index, skipReason: SkipByUser}
+	}
+	return itemResult{index: index, path: path, err: err}
+}
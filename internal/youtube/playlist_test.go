@@ -0,0 +1,208 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// drainPlaylist reads p's messages until it completes
+func drainPlaylist(t *testing.T, p *PlaylistDownload) ([]PlaylistDownloadProgressMsg, PlaylistDownloadCompleteMsg) {
+	t.Helper()
+	var items []PlaylistDownloadProgressMsg
+	for {
+		switch msg := p.Next()().(type) {
+		case PlaylistDownloadProgressMsg:
+			items = append(items, msg)
+		case PlaylistDownloadCompleteMsg:
+			return items, msg
+		case nil:
+			t.Fatal("playlist stream ended without a PlaylistDownloadCompleteMsg")
+		}
+	}
+}
+
+func TestPlaylistDownload(t *testing.T) {
+	fake := NewFakeBackend()
+	items := []SearchResult{
+		fake.AddVideo(VideoMetadata{ID: "aaaaaaaaaaa", Title: "A - One"}),
+		fake.AddVideo(VideoMetadata{ID: "bbbbbbbbbbb", Title: "B - Two"}),
+		fake.AddVideo(VideoMetadata{ID: "ccccccccccc", Title: "C - Three"}),
+		fake.AddVideo(VideoMetadata{ID: "ddddddddddd", Title: "D - Four"}),
+		fake.AddVideo(VideoMetadata{ID: "eeeeeeeeeee", Title: "E - Five"}),
+	}
+	fake.DownloadErrors["bbbbbbbbbbb"] = &DownloadError{Category: CategoryPrivate, Message: "Private video"}
+	fake.DownloadErrors["ddddddddddd"] = &Warning{Err: errors.New("cover art: no thumbnail")}
+
+	p := StartPlaylistDownload(context.Background(), fake, PlaylistJob{
+		Name:    "Mix",
+		Items:   items,
+		Workers: 2,
+		Options: DownloadOptions{Template: "{playlist}/{index} - {title}"},
+		Skip:    func(id string) bool { return id == "ccccccccccc" },
+	})
+	progress, done := drainPlaylist(t, p)
+
+	if done.Success != 3 || done.Failed != 1 || done.Skipped != 1 || done.Cancelled || done.Aborted {
+		t.Errorf("complete = %+v, want 3 succeeded, 1 failed, 1 skipped", done)
+	}
+	want := map[ErrorCategory][]string{CategoryPrivate: {"B - Two: Private video"}}
+	if !reflect.DeepEqual(done.FailedByCategory, want) {
+		t.Errorf("FailedByCategory = %v, want %v", done.FailedByCategory, want)
+	}
+	if len(progress) != len(items) {
+		t.Fatalf("got %d progress messages, want %d", len(progress), len(items))
+	}
+	for i, msg := range progress {
+		if msg.Current != i+1 || msg.Total != len(items) {
+			t.Errorf("progress %d counts %d/%d", i, msg.Current, msg.Total)
+		}
+		switch items[msg.Index].ID {
+		case "bbbbbbbbbbb":
+			if msg.Success || msg.Skipped {
+				t.Errorf("failed item = %+v", msg)
+			}
+		case "ccccccccccc":
+			if !msg.Skipped || msg.SkipReason != SkipArchived {
+				t.Errorf("archived item = %+v, want skipped", msg)
+			}
+		case "ddddddddddd":
+			if !msg.Success || msg.Warning == "" {
+				t.Errorf("warned item = %+v, want a success with a warning", msg)
+			}
+		default:
+			if !msg.Success {
+				t.Errorf("item %q didn't succeed", msg.Title)
+			}
+		}
+		if msg.Index == 3 && msg.Path != "Mix/04 - Four.mp3" {
+			t.Errorf("item 4 saved to %q", msg.Path)
+		}
+	}
+	if got := len(fake.Downloads()); got != len(items)-1 {
+		t.Errorf("backend received %d downloads, want %d", got, len(items)-1)
+	}
+}
+
+func TestPlaylistDownloadIndexes(t *testing.T) {
+	fake := NewFakeBackend()
+	items := []SearchResult{
+		fake.AddVideo(VideoMetadata{ID: "aaaaaaaaaaa", Title: "One"}),
+		fake.AddVideo(VideoMetadata{ID: "bbbbbbbbbbb", Title: "Two"}),
+		fake.AddVideo(VideoMetadata{ID: "ccccccccccc", Title: "Three"}),
+	}
+	p := StartPlaylistDownload(context.Background(), fake, PlaylistJob{Items: items, Indexes: []int{2}})
+	progress, done := drainPlaylist(t, p)
+
+	if done.Success != 1 || len(progress) != 1 || progress[0].Index != 2 || progress[0].Current != 3 {
+		t.Errorf("resumed download = %+v, %+v, want only the third item", progress, done)
+	}
+	if got := fake.Downloads(); len(got) != 1 || got[0].VideoID != "ccccccccccc" || got[0].PlaylistIndex != 3 {
+		t.Errorf("downloads = %+v, want only the third item", got)
+	}
+}
+
+func TestPlaylistDownloadControls(t *testing.T) {
+	fake := NewFakeBackend()
+	fake.Delay = 5 * time.Millisecond
+	items := []SearchResult{
+		fake.AddVideo(VideoMetadata{ID: "aaaaaaaaaaa", Title: "One"}),
+		fake.AddVideo(VideoMetadata{ID: "bbbbbbbbbbb", Title: "Two"}),
+		fake.AddVideo(VideoMetadata{ID: "ccccccccccc", Title: "Three"}),
+	}
+
+	t.Run("skip and abort", func(t *testing.T) {
+		p := StartPlaylistDownload(context.Background(), fake, PlaylistJob{Items: items, Workers: 1})
+		var progress []PlaylistDownloadProgressMsg
+		for {
+			msg := p.Next()()
+			if started, ok := msg.(PlaylistItemStartedMsg); ok && started.Index == 0 {
+				// Skip the first song while it downloads, then stop
+				p.Abort()
+				if !p.Skip(0) {
+					t.Fatal("Skip() of the item in flight = false")
+				}
+			}
+			if msg, ok := msg.(PlaylistDownloadProgressMsg); ok {
+				progress = append(progress, msg)
+			}
+			if done, ok := msg.(PlaylistDownloadCompleteMsg); ok {
+				if !done.Aborted || done.Cancelled || done.Skipped != 1 {
+					t.Errorf("complete = %+v, want an abort after 1 skipped", done)
+				}
+				break
+			}
+		}
+		if len(progress) != 1 || !progress[0].Skipped || progress[0].SkipReason != SkipByUser {
+			t.Errorf("progress = %+v, want the first song skipped by the user", progress)
+		}
+		if p.Skip(1) {
+			t.Error("Skip() of an item that never started = true")
+		}
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		p := StartPlaylistDownload(context.Background(), fake, PlaylistJob{Items: items, Workers: 1})
+		p.Pause()
+		if !p.Paused() {
+			t.Fatal("Paused() = false after Pause()")
+		}
+		p.Resume()
+		if _, done := drainPlaylist(t, p); done.Success != len(items) {
+			t.Errorf("complete = %+v, want every item after resuming", done)
+		}
+	})
+}
+
+func TestPlaylistDownloadAlbum(t *testing.T) {
+	fake := NewFakeBackend()
+	items := []SearchResult{
+		fake.AddVideo(VideoMetadata{ID: "aaaaaaaaaaa", Title: "Band - One"}),
+		fake.AddVideo(VideoMetadata{ID: "bbbbbbbbbbb", Title: "Band - Two (feat. Guest)"}),
+	}
+	album := NewAlbum("Album - Greatest Hits", items)
+	if album != (Album{Title: "Greatest Hits", Artist: "Band"}) {
+		t.Fatalf("NewAlbum() = %+v", album)
+	}
+
+	p := StartPlaylistDownload(context.Background(), fake, PlaylistJob{Name: "Album - Greatest Hits", Items: items, Album: &album})
+	progress, _ := drainPlaylist(t, p)
+
+	paths := map[string]string{}
+	for _, msg := range progress {
+		paths[msg.ID] = msg.Path
+	}
+	if paths["bbbbbbbbbbb"] != "Greatest Hits/02 - Two.mp3" {
+		t.Errorf("album track path = %q", paths["bbbbbbbbbbb"])
+	}
+	for _, req := range fake.Downloads() {
+		if req.Tags.Album != "Greatest Hits" || req.Tags.AlbumArtist != "Band" || req.Tags.Track != "1/2" && req.Tags.Track != "2/2" {
+			t.Errorf("album track tags = %+v", req.Tags)
+		}
+	}
+	if got := len(fake.Thumbnails()); got != 1 {
+		t.Errorf("fetched %d thumbnails for the album cover, want 1", got)
+	}
+}
+
+func TestWritesCoverFile(t *testing.T) {
+	tests := []struct {
+		template string
+		index    int
+		want     bool
+	}{
+		{"{album}/{index} - {title}", 1, true},
+		{"{playlist}/{title}", 1, true},
+		{"{artist}/{playlist} - {title}", 1, false},
+		{"{title}", 1, false},
+		{"{playlist}/{title}", 0, false},
+	}
+	for _, tt := range tests {
+		req := DownloadRequest{PlaylistIndex: tt.index, DownloadOptions: DownloadOptions{Template: tt.template, CoverFile: "folder.jpg"}}
+		if got := req.writesCoverFile(); got != tt.want {
+			t.Errorf("writesCoverFile() with %q, index %d = %v, want %v", tt.template, tt.index, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package youtube
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// processes tracks the running yt-dlp commands so they can be killed when
+// the program exits. They run in their own process group and would
+// otherwise keep downloading in the background.
+var processes = struct {
+	sync.Mutex
+	cmds map[*exec.Cmd]struct{}
+}{cmds: map[*exec.Cmd]struct{}{}}
+
+// startProcess starts cmd and tracks it until waitProcess
+func startProcess(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	processes.Lock()
+	processes.cmds[cmd] = struct{}{}
+	processes.Unlock()
+	return nil
+}
+
+// waitProcess waits for cmd to exit and stops tracking it
+func waitProcess(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	processes.Lock()
+	delete(processes.cmds, cmd)
+	processes.Unlock()
+	return err
+}
+
+// KillAll kills every running yt-dlp process along with the ffmpeg
+// processes it started. Call it before exiting.
+func KillAll() {
+	processes.Lock()
+	defer processes.Unlock()
+	for cmd := range processes.cmds {
+		killProcessGroup(cmd)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package youtube
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups aren't available;
+// cancellation kills yt-dlp itself
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,175 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Download stages reported in Progress.Stage
+const (
+	StageDownloading = "downloading"
+	StageConverting  = "converting"
+	StageThumbnail   = "embedding thumbnail"
+	StageMetadata    = "adding metadata"
+	StageProcessing  = "post-processing"
+	StageRetrying    = "retrying"
+)
+
+// Progress is a snapshot of a running download
+type Progress struct {
+	Stage      string
+	Percent    float64 // 0-100, only meaningful while downloading
+	Downloaded int64   // bytes
+	Total      int64   // bytes, 0 when unknown
+	Speed      float64 // bytes per second, 0 when unknown
+	ETA        int     // seconds, -1 when unknown; while retrying, the wait
+}
+
+// ErrCancelled is returned for downloads stopped by the user
+var ErrCancelled = errors.New("cancelled")
+
+// ProgressFunc receives progress updates from a Backend download
+type ProgressFunc func(Progress)
+
+// DownloadProgressMsg is sent whenever a running download reports progress
+type DownloadProgressMsg struct {
+	Download *Download
+	Progress Progress
+}
+
+// Download streams the progress of a running download into the bubbletea
+// program. Each message carries the handle so Update can match it to its
+// job and keep listening.
+type Download struct {
+	updates chan tea.Msg
+	cancel  context.CancelFunc
+}
+
+// StartDownload runs req on b in the background. Progress arrives as
+// DownloadProgressMsg, followed by a final DownloadCompleteMsg; read them
+// with Next.
+func StartDownload(b Backend, req DownloadRequest) *Download {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Download{updates: make(chan tea.Msg, 1), cancel: cancel}
+	go func() {
+		defer cancel()
+		path, err := b.Download(ctx, req, func(p Progress) {
+			// Drop updates the UI hasn't caught up with; the next one
+			// supersedes them anyway
+			select {
+			case d.updates <- DownloadProgressMsg{Download: d, Progress: p}:
+			default:
+			}
+		})
+		// A song saved before the cancellation took effect is kept
+		var warning string
+		if IsWarning(err) {
+			warning, err = err.Error(), nil
+		} else if err != nil && ctx.Err() != nil {
+			err = ErrCancelled
+		} else if err != nil {
+			err = fmt.Errorf("download failed: %w", err)
+		}
+		d.updates <- DownloadCompleteMsg{Download: d, Path: path, Warning: warning, Err: err}
+		close(d.updates)
+	}()
+	return d
+}
+
+// Cancel stops the download. The stream still ends with a
+// DownloadCompleteMsg carrying ErrCancelled.
+func (d *Download) Cancel() {
+	d.cancel()
+}
+
+// Next waits for the next message of the download stream
+func (d *Download) Next() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-d.updates
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Prefixes of the machine-readable lines printed by yt-dlp
+const (
+	downloadLinePrefix    = "[dl]"
+	postprocessLinePrefix = "[pp]"
+	filepathLinePrefix    = "[file]"
+	partialLinePrefix     = "[tmp]"
+)
+
+// ytdlpProgressArgs makes yt-dlp print one machine-readable line per update
+var ytdlpProgressArgs = []string{
+	"--newline",
+	"--progress",
+	"--progress-template", "download:" + downloadLinePrefix +
+		"%(progress.downloaded_bytes)s|%(progress.total_bytes)s|%(progress.total_bytes_estimate)s|%(progress.speed)s|%(progress.eta)s",
+	"--progress-template", "postprocess:" + postprocessLinePrefix +
+		"%(progress.postprocessor)s|%(progress.status)s",
+}
+
+// parseProgressLine parses a line produced by ytdlpProgressArgs
+func parseProgressLine(line string) (Progress, bool) {
+	line = strings.TrimSpace(line)
+
+	if rest, ok := strings.CutPrefix(line, downloadLinePrefix); ok {
+		fields := strings.Split(rest, "|")
+		if len(fields) != 5 {
+			return Progress{}, false
+		}
+		p := Progress{
+			Stage:      StageDownloading,
+			Downloaded: int64(parseNumber(fields[0])),
+			Total:      int64(parseNumber(fields[1])),
+			Speed:      parseNumber(fields[3]),
+			ETA:        -1,
+		}
+		if p.Total == 0 {
+			p.Total = int64(parseNumber(fields[2]))
+		}
+		if p.Total > 0 {
+			p.Percent = float64(p.Downloaded) / float64(p.Total) * 100
+		}
+		if fields[4] != "NA" {
+			p.ETA = int(parseNumber(fields[4]))
+		}
+		return p, true
+	}
+
+	if rest, ok := strings.CutPrefix(line, postprocessLinePrefix); ok {
+		postprocessor, _, _ := strings.Cut(rest, "|")
+		return Progress{Stage: postprocessStage(postprocessor), Percent: 100, ETA: -1}, true
+	}
+
+	return Progress{}, false
+}
+
+// postprocessStage maps a yt-dlp postprocessor name to a readable stage
+func postprocessStage(postprocessor string) string {
+	switch {
+	case strings.Contains(postprocessor, "ExtractAudio"):
+		return StageConverting
+	case strings.Contains(postprocessor, "Thumbnail"):
+		return StageThumbnail
+	case strings.Contains(postprocessor, "Metadata"):
+		return StageMetadata
+	}
+	return StageProcessing
+}
+
+// parseNumber parses a yt-dlp template value, treating "NA" as zero
+func parseNumber(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,132 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Progress
+		ok   bool
+	}{
+		{
+			name: "download with total",
+			line: "[dl]1048576|4194304|NA|524288.5|6",
+			want: Progress{Stage: StageDownloading, Percent: 25, Downloaded: 1 << 20, Total: 4 << 20, Speed: 524288.5, ETA: 6},
+			ok:   true,
+		},
+		{
+			name: "download with estimated total",
+			line: "[dl]2097152|NA|4194304|NA|NA",
+			want: Progress{Stage: StageDownloading, Percent: 50, Downloaded: 2 << 20, Total: 4 << 20, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "download with unknown total",
+			line: "  [dl]100|NA|NA|NA|NA  ",
+			want: Progress{Stage: StageDownloading, Downloaded: 100, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "malformed download line",
+			line: "[dl]100|200",
+		},
+		{
+			name: "audio extraction",
+			line: "[pp]FFmpegExtractAudio|started",
+			want: Progress{Stage: StageConverting, Percent: 100, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "thumbnail embedding",
+			line: "[pp]EmbedThumbnail|started",
+			want: Progress{Stage: StageThumbnail, Percent: 100, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "metadata",
+			line: "[pp]FFmpegMetadata|finished",
+			want: Progress{Stage: StageMetadata, Percent: 100, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "other postprocessor",
+			line: "[pp]MoveFiles|started",
+			want: Progress{Stage: StageProcessing, Percent: 100, ETA: -1},
+			ok:   true,
+		},
+		{
+			name: "unrelated output",
+			line: "[youtube] abc: Downloading webpage",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProgressLine(tt.line)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseProgressLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// finishingBackend saves the song, then fails its finishing touches with
+// the cancellation it waits for
+type finishingBackend struct {
+	*FakeBackend
+	started chan struct{}
+}
+
+func (b finishingBackend) Download(ctx context.Context, req DownloadRequest, progress ProgressFunc) (string, error) {
+	close(b.started)
+	<-ctx.Done()
+	return "Song.mp3", &Warning{Err: ctx.Err()}
+}
+
+func TestStartDownload(t *testing.T) {
+	fake := NewFakeBackend()
+	fake.DownloadErrors["bbbbbbbbbbb"] = &Warning{Err: errors.New("cover art: no thumbnail")}
+	fake.DownloadErrors["ccccccccccc"] = &DownloadError{Category: CategoryPrivate, Message: "Private video"}
+
+	tests := []struct {
+		name        string
+		b           Backend
+		id          string
+		cancel      bool
+		wantPath    bool
+		wantWarning string
+		wantErr     error
+	}{
+		{name: "success", b: fake, id: "aaaaaaaaaaa", wantPath: true},
+		{name: "warning", b: fake, id: "bbbbbbbbbbb", wantPath: true, wantWarning: "cover art: no thumbnail"},
+		{name: "failure", b: fake, id: "ccccccccccc", wantErr: ErrPrivate},
+		{name: "cancelled", b: &FakeBackend{Delay: time.Hour}, id: "aaaaaaaaaaa", cancel: true, wantErr: ErrCancelled},
+		{name: "cancelled after saving", b: finishingBackend{started: make(chan struct{})}, id: "aaaaaaaaaaa", cancel: true,
+			wantPath: true, wantWarning: context.Canceled.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := StartDownload(tt.b, DownloadRequest{VideoID: tt.id, Title: "Song"})
+			if tt.cancel {
+				if b, ok := tt.b.(finishingBackend); ok {
+					<-b.started
+				}
+				d.Cancel()
+			}
+			var done DownloadCompleteMsg
+			for msg := d.Next()(); msg != nil; msg = d.Next()() {
+				if m, ok := msg.(DownloadCompleteMsg); ok {
+					done = m
+				}
+			}
+			if (done.Path != "") != tt.wantPath || done.Warning != tt.wantWarning || !errors.Is(done.Err, tt.wantErr) {
+				t.Errorf("DownloadCompleteMsg = %+v, want path %v, warning %q, error %v", done, tt.wantPath, tt.wantWarning, tt.wantErr)
+			}
+		})
+	}
+}
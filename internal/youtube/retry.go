@@ -0,0 +1,66 @@
+package youtube
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultRetries is how many times a transient download failure is retried
+const DefaultRetries = 3
+
+// RetryBackend wraps a Backend and retries downloads that failed for a
+// transient reason (network errors, HTTP 429 and 5xx) with exponential
+// backoff and jitter. Permanent failures are returned right away.
+type RetryBackend struct {
+	Backend
+	// Retries is the number of extra attempts after the first failure
+	Retries int
+	// BaseDelay is the wait before the first retry; it doubles every attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration
+}
+
+// WithRetries returns b retrying transient download failures up to retries
+// times. With retries < 1, b is returned unchanged.
+func WithRetries(b Backend, retries int) Backend {
+	if retries < 1 {
+		return b
+	}
+	return &RetryBackend{Backend: b, Retries: retries, BaseDelay: 2 * time.Second, MaxDelay: 30 * time.Second}
+}
+
+// Download downloads through the wrapped backend, retrying transient
+// failures. A StageRetrying progress update is reported before each wait.
+func (r *RetryBackend) Download(ctx context.Context, req DownloadRequest, progress ProgressFunc) (string, error) {
+	for attempt := 0; ; attempt++ {
+		path, err := r.Backend.Download(ctx, req, progress)
+		// Warnings come with a saved song, which is never downloaded again
+		if err == nil || IsWarning(err) || attempt >= r.Retries || !CategoryOf(err).Transient() || ctx.Err() != nil {
+			return path, err
+		}
+
+		delay := r.backoff(attempt)
+		if progress != nil {
+			progress(Progress{Stage: StageRetrying, ETA: int(delay.Seconds())})
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the wait before retry number attempt+1: the base delay
+// doubled per attempt and capped, with the upper half randomized so
+// parallel workers don't retry in lockstep
+func (r *RetryBackend) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay << attempt
+	if delay > r.MaxDelay || delay <= 0 {
+		delay = r.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,94 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryBackend(t *testing.T) {
+	tests := []struct {
+		name      string
+		transient int
+		permanent error
+		retries   int
+		wantErr   bool
+		wantCalls int
+		wantWaits int
+	}{
+		{name: "success", retries: 3, wantCalls: 1},
+		{name: "transient failures recover", transient: 2, retries: 3, wantCalls: 3, wantWaits: 2},
+		{name: "retries run out", transient: 5, retries: 2, wantErr: true, wantCalls: 3, wantWaits: 2},
+		{name: "permanent failure", permanent: &DownloadError{Category: CategoryPrivate}, retries: 3,
+			wantErr: true, wantCalls: 1},
+		{name: "warnings are never retried", permanent: &Warning{Err: &DownloadError{Category: CategoryNetwork}}, retries: 3,
+			wantErr: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeBackend()
+			fake.TransientFailures["abc123def45"] = tt.transient
+			if tt.permanent != nil {
+				fake.DownloadErrors["abc123def45"] = tt.permanent
+			}
+			b := &RetryBackend{Backend: fake, Retries: tt.retries, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+			var waits int
+			_, err := b.Download(context.Background(), DownloadRequest{VideoID: "abc123def45"}, func(p Progress) {
+				if p.Stage == StageRetrying {
+					waits++
+				}
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Download() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := len(fake.Downloads()); got != tt.wantCalls {
+				t.Errorf("Download() made %d attempts, want %d", got, tt.wantCalls)
+			}
+			if waits != tt.wantWaits {
+				t.Errorf("Download() reported %d retries, want %d", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestRetryBackendCancel(t *testing.T) {
+	fake := NewFakeBackend()
+	fake.TransientFailures["abc123def45"] = 1
+	b := &RetryBackend{Backend: fake, Retries: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := b.Download(ctx, DownloadRequest{VideoID: "abc123def45"}, func(p Progress) {
+		if p.Stage == StageRetrying {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Download() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := &RetryBackend{BaseDelay: 2 * time.Second, MaxDelay: 30 * time.Second}
+	for attempt, max := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		for range 20 {
+			if d := r.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, d, max/2, max)
+			}
+		}
+	}
+	if d := r.backoff(100); d < 15*time.Second || d > 30*time.Second {
+		t.Errorf("backoff(100) = %v, want the capped delay", d)
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	fake := NewFakeBackend()
+	if b := WithRetries(fake, 0); b != Backend(fake) {
+		t.Error("WithRetries(b, 0) wrapped the backend")
+	}
+	if b, ok := WithRetries(fake, 2).(*RetryBackend); !ok || b.Retries != 2 {
+		t.Errorf("WithRetries(b, 2) = %#v", b)
+	}
+}
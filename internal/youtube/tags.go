@@ -0,0 +1,79 @@
+package youtube
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tags are the metadata tags written into a downloaded file. Empty fields
+// are left as yt-dlp writes them.
+type Tags struct {
+	// Artist may list several artists separated by "; "
+	Artist      string
+	Title       string
+	Album       string
+	AlbumArtist string
+	Year        string
+	Genre       string
+	// Track is the track number, optionally with the total, e.g. "3/12"
+	Track   string
+	Comment string
+}
+
+var (
+	yearPattern  = regexp.MustCompile(`^\d{4}$`)
+	trackPattern = regexp.MustCompile(`^\d+(/\d+)?$`)
+)
+
+// Validate reports a malformed year or track number
+func (t Tags) Validate() error {
+	if t.Year != "" && !yearPattern.MatchString(t.Year) {
+		return fmt.Errorf("year %q must have four digits", t.Year)
+	}
+	if t.Track != "" && !trackPattern.MatchString(t.Track) {
+		return fmt.Errorf("track %q must be a number like 3 or 3/12", t.Track)
+	}
+	return nil
+}
+
+// tagField is a tag and the ffmpeg metadata key it is written as
+type tagField struct {
+	key   string
+	value string
+}
+
+func (t Tags) fields() []tagField {
+	return []tagField{
+		{"artist", t.Artist},
+		{"title", t.Title},
+		{"album", t.Album},
+		{"album_artist", t.AlbumArtist},
+		{"date", t.Year},
+		{"genre", t.Genre},
+		{"track", t.Track},
+		{"comment", t.Comment},
+	}
+}
+
+// WrittenTags returns the tags written into req's file: the artist and
+// title parsed from the video title, replaced by any field set in req.Tags
+func (req DownloadRequest) WrittenTags() Tags {
+	tags := req.Tags
+	if req.Title != "" {
+		track := req.Track()
+		if tags.Artist == "" {
+			tags.Artist = track.ArtistTag()
+		}
+		if tags.Title == "" {
+			tags.Title = track.Title
+		}
+	}
+	return tags
+}
+
+// artistName turns a multi-value artist tag into a name for filenames,
+// e.g. "Artist; Guest" becomes "Artist, Guest"
+func artistName(tag string) string {
+	return strings.ReplaceAll(tag, "; ", ", ")
+}
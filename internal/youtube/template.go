@@ -0,0 +1,165 @@
+package youtube
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/adelapazborrero/music_download/internal/coverart"
+	"github.com/adelapazborrero/music_download/internal/lyrics"
+)
+
+// DefaultTemplate is the filename template used when none is configured
+const DefaultTemplate = "{artist} - {title}"
+
+// TemplateTokens lists the tokens understood in filename templates
+var TemplateTokens = []string{"artist", "title", "album", "playlist", "index", "date", "id"}
+
+// ytdlpTemplateFields maps template tokens to yt-dlp output template fields,
+// used when the value isn't known before the download starts
+var ytdlpTemplateFields = map[string]string{
+	"artist":   "%(artist,creator,uploader|Unknown Artist)s",
+	"title":    "%(title)s",
+	"album":    "%(album|Unknown Album)s",
+	"playlist": "",
+	"index":    "",
+	"date":     "%(upload_date>%Y-%m-%d|)s",
+	"id":       "%(id)s",
+}
+
+var tokenPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// DownloadOptions controls where and how downloaded files are written
+type DownloadOptions struct {
+	// OutputDir is the root directory downloads are written to
+	OutputDir string `json:"output_dir"`
+	// Template is the filename template relative to OutputDir, without
+	// extension, e.g. "{artist}/{album}/{index} - {title}"
+	Template string `json:"filename_template"`
+	// Format and Quality select the audio codec and encoding preset
+	Format  AudioFormat `json:"audio_format"`
+	Quality Quality     `json:"audio_quality"`
+	// CoverArt controls how thumbnails are made into embedded cover art
+	CoverArt coverart.Options `json:"cover_art"`
+	// CoverFile names an image, e.g. "folder.jpg", saved next to playlist
+	// downloads; empty saves none
+	CoverFile string `json:"cover_file,omitempty"`
+	// Lyrics controls saving subtitles as synced lyrics
+	Lyrics lyrics.Options `json:"lyrics"`
+}
+
+// ValidateTemplate reports unknown tokens, absolute paths and paths leaving
+// the output directory in tmpl
+func ValidateTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("filename template is empty")
+	}
+	if filepath.IsAbs(tmpl) {
+		return fmt.Errorf("filename template %q must be relative to the output directory", tmpl)
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(filepath.Clean(tmpl)), "/") {
+		if segment == ".." {
+			return fmt.Errorf("filename template %q must stay inside the output directory", tmpl)
+		}
+	}
+	for _, match := range tokenPattern.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := ytdlpTemplateFields[match[1]]; !ok {
+			return fmt.Errorf("unknown token {%s} in filename template (valid: {%s})",
+				match[1], strings.Join(TemplateTokens, "}, {"))
+		}
+	}
+	return nil
+}
+
+// Destination describes where req will be saved, with unresolved tokens
+// left in place. Used for display before the real path is known.
+func (req DownloadRequest) Destination() string {
+	name := expandTemplate(req.template(), func(token string) string {
+		if v := req.knownValue(token); v != "" {
+			return sanitizeFilename(v)
+		}
+		return "{" + token + "}"
+	})
+	return filepath.Join(req.outputDir(), name) + "." + req.extension()
+}
+
+// ytdlpOutputTemplate converts req's filename template into yt-dlp's -o
+// syntax. Everything but the yt-dlp fields is literal text, so the output
+// directory, the template text and the known values are escaped.
+func (req DownloadRequest) ytdlpOutputTemplate() string {
+	name := expandTemplate(ytdlpEscape(req.template()), func(token string) string {
+		if v := req.knownValue(token); v != "" {
+			return ytdlpEscape(sanitizeFilename(v))
+		}
+		return ytdlpTemplateFields[token]
+	})
+	return filepath.Join(ytdlpEscape(req.outputDir()), name) + ".%(ext)s"
+}
+
+// ytdlpEscape escapes s for literal use in a yt-dlp output template
+func ytdlpEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// knownValue returns the value of token if it is known before downloading
+func (req DownloadRequest) knownValue(token string) string {
+	switch token {
+	case "artist":
+		return artistName(req.WrittenTags().Artist)
+	case "title":
+		return req.WrittenTags().Title
+	case "album":
+		return req.Tags.Album
+	case "playlist":
+		return req.Playlist
+	case "index":
+		if req.PlaylistIndex > 0 {
+			return fmt.Sprintf("%02d", req.PlaylistIndex)
+		}
+	case "id":
+		return req.VideoID
+	}
+	return ""
+}
+
+// Track returns the artist and song title parsed from req's video title
+func (req DownloadRequest) Track() Track {
+	return ParseTitle(req.Title, req.Channel)
+}
+
+func (req DownloadRequest) template() string {
+	if req.Template == "" {
+		return DefaultTemplate
+	}
+	return req.Template
+}
+
+func (req DownloadRequest) outputDir() string {
+	if req.OutputDir == "" {
+		return "."
+	}
+	return req.OutputDir
+}
+
+// expandTemplate replaces every {token} in tmpl with lookup(token)
+func expandTemplate(tmpl string, lookup func(token string) string) string {
+	return tokenPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		return lookup(match[1 : len(match)-1])
+	})
+}
+
+// filenameReplacer replaces characters that can't appear in a path segment
+var filenameReplacer = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_", "?", "_",
+	"\"", "_", "<", "_", ">", "_", "|", "_",
+)
+
+// sanitizeFilename makes s safe to use as a single path segment
+func sanitizeFilename(s string) string {
+	s = strings.TrimSpace(filenameReplacer.Replace(s))
+	if s == "." || s == ".." {
+		return strings.Repeat("_", len(s))
+	}
+	return s
+}
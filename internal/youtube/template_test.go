@@ -0,0 +1,170 @@
+package youtube
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr string
+	}{
+		{"{title}", ""},
+		{"{artist}/{album}/{index} - {title}", ""},
+		{"{playlist}/{date} {id}", ""},
+		{"music/../{title}", ""},
+		{"", "empty"},
+		{"   ", "empty"},
+		{"/tmp/{title}", "relative"},
+		{"../{title}", "inside the output directory"},
+		{"../../x/{title}", "inside the output directory"},
+		{"{artist}/../../{title}", "inside the output directory"},
+		{"{year}/{title}", "unknown token {year}"},
+	}
+	for _, tt := range tests {
+		err := ValidateTemplate(tt.tmpl)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ValidateTemplate(%q) = %v, want nil", tt.tmpl, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ValidateTemplate(%q) = %v, want an error mentioning %q", tt.tmpl, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		name string
+		req  DownloadRequest
+		want string
+	}{
+		{
+			name: "default template",
+			req:  DownloadRequest{VideoID: "abc123def45", Title: "Artist - Song (Official Video)"},
+			want: "Artist - Song.mp3",
+		},
+		{
+			name: "artist folder",
+			req: DownloadRequest{VideoID: "abc123def45", Title: "A ft. B - Song",
+				DownloadOptions: DownloadOptions{OutputDir: "/music", Template: "{artist}/{title}", Format: FormatFLAC}},
+			want: "/music/A, B/Song.flac",
+		},
+		{
+			name: "playlist index",
+			req: DownloadRequest{VideoID: "abc123def45", Title: "Artist - Song", Playlist: "Mix: 2024", PlaylistIndex: 3,
+				DownloadOptions: DownloadOptions{Template: "{playlist}/{index} - {title}"}},
+			want: "Mix_ 2024/03 - Song.mp3",
+		},
+		{
+			name: "unknown values stay as tokens",
+			req: DownloadRequest{VideoID: "abc123def45",
+				DownloadOptions: DownloadOptions{Template: "{artist}/{album}/{id}", Quality: QualityOriginal}},
+			want: "{artist}/{album}/abc123def45.{ext}",
+		},
+		{
+			name: "dot titles can't leave the folder",
+			req: DownloadRequest{VideoID: "abc123def45", Title: "..",
+				DownloadOptions: DownloadOptions{Template: "{title}/{id}"}},
+			want: "__/abc123def45.mp3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Destination(); got != filepath.FromSlash(tt.want) {
+				t.Errorf("Destination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYTDLPOutputTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  DownloadRequest
+		want string
+	}{
+		{
+			name: "default template",
+			req:  DownloadRequest{Title: "Artist - Song", DownloadOptions: DownloadOptions{OutputDir: "out"}},
+			want: "out/Artist - Song.%(ext)s",
+		},
+		{
+			name: "known title is escaped",
+			req:  DownloadRequest{Title: "100% Song", DownloadOptions: DownloadOptions{OutputDir: "out", Template: "{title}"}},
+			want: "out/100%% Song.%(ext)s",
+		},
+		{
+			name: "directory and template text are escaped",
+			req: DownloadRequest{Title: "Off 100%", DownloadOptions: DownloadOptions{OutputDir: "/tmp/100%",
+				Template: "50% {title}"}},
+			want: "/tmp/100%%/50%% Off 100%%.%(ext)s",
+		},
+		{
+			name: "unknown values come from yt-dlp",
+			req:  DownloadRequest{VideoID: "abc123def45", DownloadOptions: DownloadOptions{Template: "{artist}/{date} {title}"}},
+			want: "%(artist,creator,uploader|Unknown Artist)s/%(upload_date>%Y-%m-%d|)s %(title)s.%(ext)s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.ytdlpOutputTemplate(); got != filepath.FromSlash(tt.want) {
+				t.Errorf("ytdlpOutputTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYTDLPAudioArgs(t *testing.T) {
+	tests := []struct {
+		opts DownloadOptions
+		want string
+	}{
+		{DownloadOptions{}, "-f bestaudio --extract-audio --audio-format mp3 --audio-quality 0"},
+		{DownloadOptions{Format: FormatOpus, Quality: QualityCBR192}, "-f bestaudio --extract-audio --audio-format opus --audio-quality 192K"},
+		{DownloadOptions{Format: FormatFLAC, Quality: QualityOriginal}, "-f bestaudio --extract-audio --audio-format best"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.opts.ytdlpAudioArgs(), " "); got != tt.want {
+			t.Errorf("ytdlpAudioArgs(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestWrittenTags(t *testing.T) {
+	req := DownloadRequest{Title: "Artist feat. Guest - Song [HD]", Channel: "Label"}
+	got := req.WrittenTags()
+	if got.Artist != "Artist; Guest" || got.Title != "Song" {
+		t.Errorf("WrittenTags() = %+v, want artist %q and title %q", got, "Artist; Guest", "Song")
+	}
+
+	req.Tags = Tags{Artist: "Edited", Album: "Album"}
+	got = req.WrittenTags()
+	if got.Artist != "Edited" || got.Title != "Song" || got.Album != "Album" {
+		t.Errorf("WrittenTags() with edits = %+v", got)
+	}
+
+	if got := (DownloadRequest{}).WrittenTags(); got != (Tags{}) {
+		t.Errorf("WrittenTags() without a title = %+v, want none", got)
+	}
+}
+
+func TestTagsValidate(t *testing.T) {
+	tests := []struct {
+		tags Tags
+		ok   bool
+	}{
+		{Tags{}, true},
+		{Tags{Year: "2024", Track: "3"}, true},
+		{Tags{Track: "3/12"}, true},
+		{Tags{Year: "24"}, false},
+		{Tags{Track: "three"}, false},
+		{Tags{Track: "3/"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.tags.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v.Validate() = %v, want ok %v", tt.tags, err, tt.ok)
+		}
+	}
+}
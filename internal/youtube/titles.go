@@ -0,0 +1,208 @@
+package youtube
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Track is the artist and song title parsed from a video title
+type Track struct {
+	// Artists lists the main artist first, followed by featured artists
+	Artists []string
+	Title   string
+}
+
+// ArtistTag returns the artists as a multi-value tag, e.g. "Artist; Guest",
+// which most players and taggers split into separate artists
+func (t Track) ArtistTag() string {
+	return strings.Join(t.Artists, "; ")
+}
+
+// topicSuffix marks YouTube's auto-generated "Artist - Topic" channels, whose
+// video titles are just the song title
+const topicSuffix = " - Topic"
+
+var (
+	// bracketPattern matches a (...), [...] or 【...】 group
+	bracketPattern = regexp.MustCompile(`\s*[(\[【]([^)\]】]*)[)\]】]`)
+	// creditPattern matches a bracketed "(feat. Artist)" or "(with Artist)"
+	creditPattern = regexp.MustCompile(`(?i)^(?:feat\.?|ft\.?|featuring|with)\s+(.+)$`)
+	// featPattern matches a featuring credit after the artist or title
+	featPattern = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+(.+)$`)
+	// dashPattern separates "Artist - Title"
+	dashPattern = regexp.MustCompile(`\s+[-–—]\s+`)
+	// artistListPattern separates featured artists
+	artistListPattern = regexp.MustCompile(`(?i)\s*(?:,|&|\band\b)\s*`)
+)
+
+// junkWords make up the bracketed and trailing suffixes dropped from titles,
+// e.g. "(Official Music Video)", "[HD]" or "| Lyric Video"
+var junkWords = map[string]bool{
+	"official": true, "officiel": true, "oficial": true,
+	"music": true, "video": true, "videoclip": true, "clip": true, "mv": true, "m/v": true,
+	"audio": true, "lyric": true, "lyrics": true, "letra": true, "with": true, "w/": true,
+	"visualizer": true, "visualiser": true, "animated": true,
+	"hd": true, "hq": true, "4k": true, "1080p": true, "720p": true,
+}
+
+// ParseTitle splits a video title such as "Artist - Title (Official Video)
+// [HD]" into a clean artist and title. Junk suffixes are dropped and
+// featured artists ("feat.", "ft.") become extra artists. When the title has
+// no artist, it is taken from the channel: "Artist - Topic" channels name
+// the artist and "ArtistVEVO" channels are unsquashed.
+func ParseTitle(title, channel string) Track {
+	var featured []string
+	clean := bracketPattern.ReplaceAllStringFunc(title, func(group string) string {
+		inner := strings.TrimSpace(bracketPattern.FindStringSubmatch(group)[1])
+		if isJunk(inner) {
+			return ""
+		}
+		if m := creditPattern.FindStringSubmatch(inner); m != nil {
+			featured = append(featured, splitArtists(m[1])...)
+			return ""
+		}
+		return group
+	})
+
+	// Drop "| Official Video" and "- Lyrics" style segments. Dash segments
+	// are only junk after the title, so "Madonna - Music" keeps its title.
+	titleParts := 2
+	if strings.HasSuffix(channel, topicSuffix) {
+		titleParts = 1
+	}
+	var parts []string
+	for i, segment := range strings.Split(clean, " | ") {
+		for _, part := range dashPattern.Split(segment, -1) {
+			part = strings.TrimSpace(part)
+			if part == "" || isJunk(part) && (i > 0 || len(parts) >= titleParts) {
+				continue
+			}
+			parts = append(parts, part)
+		}
+	}
+
+	var artist string
+	switch {
+	case strings.HasSuffix(channel, topicSuffix):
+		artist = strings.TrimSuffix(channel, topicSuffix)
+		clean = strings.Join(parts, " - ")
+	case len(parts) > 1:
+		artist = parts[0]
+		clean = strings.Join(parts[1:], " - ")
+	default:
+		artist = channelArtist(channel)
+		clean = strings.Join(parts, " - ")
+	}
+
+	if m := featPattern.FindStringSubmatchIndex(artist); m != nil {
+		featured = append(splitArtists(artist[m[2]:m[3]]), featured...)
+		artist = artist[:m[0]]
+	}
+	if m := featPattern.FindStringSubmatchIndex(clean); m != nil {
+		featured = append(featured, splitArtists(clean[m[2]:m[3]])...)
+		clean = clean[:m[0]]
+	}
+
+	track := Track{Title: trimTitle(clean)}
+	if track.Title == "" {
+		track.Title = strings.TrimSpace(title)
+	}
+	seen := map[string]bool{}
+	for _, a := range append([]string{artist}, featured...) {
+		a = strings.TrimSpace(a)
+		if a != "" && !seen[strings.ToLower(a)] {
+			seen[strings.ToLower(a)] = true
+			track.Artists = append(track.Artists, a)
+		}
+	}
+	return track
+}
+
+// ytdlpTitleArgs make yt-dlp split the artist from the title and drop junk
+// suffixes from the video title it fetched, a simpler version of ParseTitle
+// for downloads whose title isn't known beforehand. The results feed the
+// filename template and the metadata tags.
+var ytdlpTitleArgs = func() []string {
+	words := make([]string, 0, len(junkWords))
+	for w := range junkWords {
+		words = append(words, regexp.QuoteMeta(w))
+	}
+	slices.Sort(words)
+	junk := `(?:(?:` + strings.Join(words, "|") + `)[\s-]*)+`
+	return []string{
+		"--replace-in-metadata", "title", `(?i)\s*[(\[【]\s*` + junk + `[)\]】]`, "",
+		"--replace-in-metadata", "title", `(?i)\s+\|\s+` + junk + `$`, "",
+		"--replace-in-metadata", "title", `(?i)^(.+?\s+[-–—]\s+.+?)\s+[-–—]\s+` + junk + `$`, `\1`,
+		"--parse-metadata", `title:^(?P<artist>.+?)\s+[-–—]\s+(?P<title>.+)$`,
+		"--parse-metadata", `channel:^(?P<artist>.+)` + regexp.QuoteMeta(topicSuffix) + `$`,
+	}
+}()
+
+// isJunk reports whether s consists only of junk words
+func isJunk(s string) bool {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(s, "-", " ")))
+	if len(words) == 0 {
+		return false
+	}
+	for _, w := range words {
+		if !junkWords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitArtists splits a featured artist list like "A, B & C"
+func splitArtists(s string) []string {
+	var artists []string
+	for _, a := range artistListPattern.Split(s, -1) {
+		if a = strings.TrimSpace(a); a != "" {
+			artists = append(artists, a)
+		}
+	}
+	return artists
+}
+
+// channelArtist derives an artist name from a channel name, e.g.
+// "TaylorSwiftVEVO" becomes "Taylor Swift"
+func channelArtist(channel string) string {
+	name := strings.TrimSpace(strings.TrimSuffix(channel, topicSuffix))
+	for _, suffix := range []string{"VEVO", "Vevo"} {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			name = strings.TrimSpace(trimmed)
+			if !strings.Contains(name, " ") {
+				name = splitCamelCase(name)
+			}
+			break
+		}
+	}
+	return name
+}
+
+// splitCamelCase inserts a space before each capital that follows a
+// lowercase letter
+func splitCamelCase(s string) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range s {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// trimTitle removes leftover whitespace and quotes around a song title
+func trimTitle(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, q := range [][2]string{{`"`, `"`}, {"“", "”"}, {"'", "'"}} {
+		if len(s) > 2 && strings.HasPrefix(s, q[0]) && strings.HasSuffix(s, q[1]) {
+			s = strings.TrimSpace(s[len(q[0]) : len(s)-len(q[1])])
+		}
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package youtube
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		channel string
+		want    Track
+	}{
+		{
+			name:  "artist and title",
+			title: "Daft Punk - Get Lucky",
+			want:  Track{Artists: []string{"Daft Punk"}, Title: "Get Lucky"},
+		},
+		{
+			name:  "junk brackets",
+			title: "Daft Punk - Get Lucky (Official Music Video) [HD]",
+			want:  Track{Artists: []string{"Daft Punk"}, Title: "Get Lucky"},
+		},
+		{
+			name:  "junk after a bar",
+			title: "Daft Punk - Get Lucky | Official Audio",
+			want:  Track{Artists: []string{"Daft Punk"}, Title: "Get Lucky"},
+		},
+		{
+			name:  "meaningful brackets are kept",
+			title: "Queen - Bohemian Rhapsody (Remastered 2011)",
+			want:  Track{Artists: []string{"Queen"}, Title: "Bohemian Rhapsody (Remastered 2011)"},
+		},
+		{
+			name:  "bracketed featuring credit",
+			title: "Daft Punk - Get Lucky (feat. Pharrell Williams & Nile Rodgers)",
+			want:  Track{Artists: []string{"Daft Punk", "Pharrell Williams", "Nile Rodgers"}, Title: "Get Lucky"},
+		},
+		{
+			name:  "featuring after the artist",
+			title: "Calvin Harris ft. Rihanna - This Is What You Came For",
+			want:  Track{Artists: []string{"Calvin Harris", "Rihanna"}, Title: "This Is What You Came For"},
+		},
+		{
+			name:  "featuring after the title",
+			title: "Calvin Harris - This Is What You Came For featuring Rihanna",
+			want:  Track{Artists: []string{"Calvin Harris", "Rihanna"}, Title: "This Is What You Came For"},
+		},
+		{
+			name:  "duplicate artists are dropped",
+			title: "Artist - Song (feat. artist)",
+			want:  Track{Artists: []string{"Artist"}, Title: "Song"},
+		},
+		{
+			name:    "topic channel names the artist",
+			title:   "Get Lucky",
+			channel: "Daft Punk - Topic",
+			want:    Track{Artists: []string{"Daft Punk"}, Title: "Get Lucky"},
+		},
+		{
+			name:    "topic channel keeps dashes in the title",
+			title:   "Song - Live",
+			channel: "Band - Topic",
+			want:    Track{Artists: []string{"Band"}, Title: "Song - Live"},
+		},
+		{
+			name:    "VEVO channel is unsquashed",
+			title:   "Shake It Off",
+			channel: "TaylorSwiftVEVO",
+			want:    Track{Artists: []string{"Taylor Swift"}, Title: "Shake It Off"},
+		},
+		{
+			name:    "plain channel is the artist",
+			title:   "My Song (Lyrics)",
+			channel: "Some Singer",
+			want:    Track{Artists: []string{"Some Singer"}, Title: "My Song"},
+		},
+		{
+			name:  "quotes around the title",
+			title: `Artist - "Song"`,
+			want:  Track{Artists: []string{"Artist"}, Title: "Song"},
+		},
+		{
+			name:  "junk after the title",
+			title: "Artist - Song - Official Video",
+			want:  Track{Artists: []string{"Artist"}, Title: "Song"},
+		},
+		{
+			name:  "title made of junk words",
+			title: "Madonna - Music",
+			want:  Track{Artists: []string{"Madonna"}, Title: "Music"},
+		},
+		{
+			name:  "title made of junk words with dotted artist",
+			title: "India.Arie - Video",
+			want:  Track{Artists: []string{"India.Arie"}, Title: "Video"},
+		},
+		{
+			name:    "junk after a topic channel's title",
+			title:   "Song - Lyrics",
+			channel: "Band - Topic",
+			want:    Track{Artists: []string{"Band"}, Title: "Song"},
+		},
+		{
+			name:    "junk after a bar without an artist",
+			title:   "Song | Official Audio",
+			channel: "Singer",
+			want:    Track{Artists: []string{"Singer"}, Title: "Song"},
+		},
+		{
+			name:  "only junk keeps the title",
+			title: "Official Video",
+			want:  Track{Title: "Official Video"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTitle(tt.title, tt.channel)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTitle(%q, %q) = %+v, want %+v", tt.title, tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackArtistTag(t *testing.T) {
+	track := Track{Artists: []string{"A", "B", "C"}}
+	if got := track.ArtistTag(); got != "A; B; C" {
+		t.Errorf("ArtistTag() = %q, want %q", got, "A; B; C")
+	}
+	if got := artistName(track.ArtistTag()); got != "A, B, C" {
+		t.Errorf("artistName() = %q, want %q", got, "A, B, C")
+	}
+}
+
+// TestYTDLPTitleArgs runs yt-dlp's title replacements with Go's regexp,
+// which reads these patterns the same way as Python's re
+func TestYTDLPTitleArgs(t *testing.T) {
+	tests := map[string]string{
+		"Daft Punk - Get Lucky (Official Music Video) [HD]": "Daft Punk - Get Lucky",
+		"Daft Punk - Get Lucky | Official Audio":            "Daft Punk - Get Lucky",
+		"Artist - Song - Lyric Video":                       "Artist - Song",
+		"Madonna - Music":                                   "Madonna - Music",
+		"Queen - Bohemian Rhapsody (Remastered 2011)":       "Queen - Bohemian Rhapsody (Remastered 2011)",
+	}
+	for title, want := range tests {
+		got := title
+		for i := 0; i < len(ytdlpTitleArgs); i++ {
+			if ytdlpTitleArgs[i] != "--replace-in-metadata" {
+				continue
+			}
+			pattern, replacement := ytdlpTitleArgs[i+2], ytdlpTitleArgs[i+3]
+			got = regexp.MustCompile(pattern).ReplaceAllString(got, strings.ReplaceAll(replacement, `\1`, "${1}"))
+			i += 3
+		}
+		if got != want {
+			t.Errorf("yt-dlp cleans %q into %q, want %q", title, got, want)
+		}
+	}
+}
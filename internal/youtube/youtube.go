@@ -1,9 +1,11 @@
 package youtube
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,8 +13,16 @@ import (
 
 // SearchResult represents a YouTube video from search results
 type SearchResult struct {
-	Title string
-	ID    string
+	Title   string
+	ID      string
+	Channel string
+	// Query, when ID is empty, is resolved to its top search result right
+	// before downloading (used by batch downloads)
+	Query string
+
+	// err is why a batch entry couldn't be turned into an item, reported
+	// when the item is downloaded
+	err error
 }
 
 // VideoMetadata represents detailed video information
@@ -22,6 +32,22 @@ type VideoMetadata struct {
 	Duration  int    `json:"duration"`
 	ViewCount int64  `json:"view_count"`
 	ID        string `json:"id"`
+	// Album and ReleaseYear are only known for some music videos
+	Album       string `json:"album,omitempty"`
+	ReleaseYear int    `json:"release_year,omitempty"`
+	// UploadDate is formatted YYYYMMDD
+	UploadDate string `json:"upload_date,omitempty"`
+}
+
+// Year returns the release year, falling back to the upload year
+func (v VideoMetadata) Year() string {
+	if v.ReleaseYear > 0 {
+		return strconv.Itoa(v.ReleaseYear)
+	}
+	if len(v.UploadDate) >= 4 {
+		return v.UploadDate[:4]
+	}
+	return ""
 }
 
 // Messages (exported so they can be used in app package)
@@ -36,56 +62,31 @@ type MetadataFetchedMsg struct {
 }
 
 type DownloadCompleteMsg struct {
-	Err error
+	Download *Download
+	Path     string
+	// Warning describes a non-fatal problem with a successful download
+	Warning string
+	Err     error
 }
 
 type PlaylistFetchedMsg struct {
+	Title string
 	Items []SearchResult
 	Err   error
 }
 
-type PlaylistDownloadProgressMsg struct {
-	Current int
-	Total   int
-	Title   string
-	Success bool
-	Error   string
-}
-
-type PlaylistDownloadCompleteMsg struct {
-	Success     int
-	Failed      int
-	FailedItems []string
-	Err         error
-}
-
-// SearchYouTube performs a YouTube search with the given query and limit
-func SearchYouTube(query string, limit int) tea.Cmd {
+// SearchYouTube performs a YouTube search with the given query and limit.
+// Cancelling ctx ends it with ErrCancelled.
+func SearchYouTube(ctx context.Context, b Backend, query string, limit int) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("yt-dlp",
-			fmt.Sprintf("ytsearch%d:%s", limit, query),
-			"--flat-playlist",
-			"--print", "%(title)s|||%(id)s",
-		)
-
-		output, err := cmd.Output()
+		results, err := b.Search(ctx, query, limit)
+		if ctx.Err() != nil {
+			return SearchCompleteMsg{Err: ErrCancelled}
+		}
 		if err != nil {
 			return SearchCompleteMsg{Err: fmt.Errorf("search failed: %w", err)}
 		}
 
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		results := make([]SearchResult, 0, len(lines))
-
-		for _, line := range lines {
-			parts := strings.Split(line, "|||")
-			if len(parts) == 2 {
-				results = append(results, SearchResult{
-					Title: parts[0],
-					ID:    parts[1],
-				})
-			}
-		}
-
 		if len(results) == 0 {
 			return SearchCompleteMsg{Err: fmt.Errorf("no results found")}
 		}
@@ -94,60 +95,32 @@ func SearchYouTube(query string, limit int) tea.Cmd {
 	}
 }
 
-// FetchMetadata retrieves detailed metadata for a video
-func FetchMetadata(videoID string) tea.Cmd {
+// FetchMetadata retrieves detailed metadata for a video. Cancelling ctx
+// ends it with ErrCancelled.
+func FetchMetadata(ctx context.Context, b Backend, videoID string) tea.Cmd {
 	return func() tea.Msg {
-		url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
-		cmd := exec.Command("yt-dlp", "-j", url)
-
-		output, err := cmd.Output()
+		metadata, err := b.Metadata(ctx, videoID)
+		if ctx.Err() != nil {
+			return MetadataFetchedMsg{Err: ErrCancelled}
+		}
 		if err != nil {
 			return MetadataFetchedMsg{Err: fmt.Errorf("failed to fetch metadata: %w", err)}
 		}
 
-		var metadata VideoMetadata
-		if err := json.Unmarshal(output, &metadata); err != nil {
-			return MetadataFetchedMsg{Err: fmt.Errorf("failed to parse metadata: %w", err)}
-		}
-
-		return MetadataFetchedMsg{Metadata: &metadata}
+		return MetadataFetchedMsg{Metadata: metadata}
 	}
 }
 
-// DownloadVideo downloads a video as MP3
-func DownloadVideo(videoID, title string) tea.Cmd {
-	return func() tea.Msg {
-		url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
-		cmd := exec.Command("yt-dlp",
-			"-f", "bestaudio",
-			"--extract-audio",
-			"--audio-format", "mp3",
-			"--audio-quality", "0",
-			"--embed-thumbnail",
-			"--add-metadata",
-			"--quiet", // Suppress yt-dlp output to avoid UI interference
-			"--progress",
-			"-o", "%(title)s.%(ext)s",
-			url,
-		)
-
-		// Don't redirect output to avoid breaking the TUI
-		// cmd.Stdout and cmd.Stderr are nil by default, which discards output
-
-		if err := cmd.Run(); err != nil {
-			return DownloadCompleteMsg{Err: fmt.Errorf("download failed: %w", err)}
-		}
-
-		return DownloadCompleteMsg{}
-	}
-}
+// videoIDPattern matches a bare video ID. Anything else of the same length,
+// like "daft punk 1", is not one.
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
 
 // ExtractVideoID extracts the video ID from various YouTube URL formats
 func ExtractVideoID(input string) string {
 	input = strings.TrimSpace(input)
 
-	// If it's already just an ID (11 characters)
-	if len(input) == 11 && !strings.Contains(input, "/") && !strings.Contains(input, ".") {
+	// If it's already just an ID
+	if videoIDPattern.MatchString(input) {
 		return input
 	}
 
@@ -196,9 +169,13 @@ func ExtractVideoID(input string) string {
 	return ""
 }
 
-// CheckDependencies verifies that required tools are installed
+// CheckDependencies verifies that the tools needed by the interactive UI are installed
 func CheckDependencies() error {
-	required := []string{"yt-dlp", "mpv", "ffmpeg"}
+	return CheckTools("yt-dlp", "mpv", "ffmpeg")
+}
+
+// CheckTools verifies that each of the given executables is in PATH
+func CheckTools(required ...string) error {
 	for _, cmd := range required {
 		if _, err := exec.LookPath(cmd); err != nil {
 			return fmt.Errorf("Required tool '%s' is not installed. Please install it first", cmd)
@@ -227,96 +204,22 @@ func ExtractPlaylistID(input string) string {
 	return ""
 }
 
-// FetchPlaylistItems retrieves all items from a YouTube playlist
-func FetchPlaylistItems(playlistID string) tea.Cmd {
+// FetchPlaylistItems retrieves all items from a YouTube playlist.
+// Cancelling ctx ends it with ErrCancelled.
+func FetchPlaylistItems(ctx context.Context, b Backend, playlistID string) tea.Cmd {
 	return func() tea.Msg {
-		url := fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID)
-		cmd := exec.Command("yt-dlp",
-			"--flat-playlist",
-			"--print", "%(title)s|||%(id)s",
-			url,
-		)
-
-		output, err := cmd.Output()
+		playlist, err := b.Playlist(ctx, playlistID)
+		if ctx.Err() != nil {
+			return PlaylistFetchedMsg{Err: ErrCancelled}
+		}
 		if err != nil {
 			return PlaylistFetchedMsg{Err: fmt.Errorf("failed to fetch playlist: %w", err)}
 		}
 
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		items := make([]SearchResult, 0, len(lines))
-
-		for _, line := range lines {
-			parts := strings.Split(line, "|||")
-			if len(parts) == 2 {
-				items = append(items, SearchResult{
-					Title: parts[0],
-					ID:    parts[1],
-				})
-			}
-		}
-
-		if len(items) == 0 {
+		if len(playlist.Items) == 0 {
 			return PlaylistFetchedMsg{Err: fmt.Errorf("no items found in playlist")}
 		}
 
-		return PlaylistFetchedMsg{Items: items}
-	}
-}
-
-// DownloadPlaylist initiates playlist download by downloading the first item
-func DownloadPlaylist(items []SearchResult) tea.Cmd {
-	return DownloadNextPlaylistItem(items, 0, 0, 0, []string{})
-}
-
-// DownloadNextPlaylistItem downloads a single playlist item and returns a command to continue
-func DownloadNextPlaylistItem(items []SearchResult, current, success, failed int, failedItems []string) tea.Cmd {
-	return func() tea.Msg {
-		// Check if we're done
-		if current >= len(items) {
-			return PlaylistDownloadCompleteMsg{
-				Success:     success,
-				Failed:      failed,
-				FailedItems: failedItems,
-			}
-		}
-
-		// Download current item
-		item := items[current]
-		url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", item.ID)
-		cmd := exec.Command("yt-dlp",
-			"-f", "bestaudio",
-			"--extract-audio",
-			"--audio-format", "mp3",
-			"--audio-quality", "0",
-			"--embed-thumbnail",
-			"--add-metadata",
-			"--quiet",
-			"--no-warnings",
-			"-o", "%(title)s.%(ext)s",
-			url,
-		)
-
-		err := cmd.Run()
-		var errMsg string
-		var downloadSuccess bool
-
-		if err != nil {
-			failed++
-			errMsg = err.Error()
-			failedItems = append(failedItems, fmt.Sprintf("%s: %s", item.Title, errMsg))
-			downloadSuccess = false
-		} else {
-			success++
-			downloadSuccess = true
-		}
-
-		// Send progress message
-		return PlaylistDownloadProgressMsg{
-			Current: current + 1,
-			Total:   len(items),
-			Title:   item.Title,
-			Success: downloadSuccess,
-			Error:   errMsg,
-		}
+		return PlaylistFetchedMsg{Title: playlist.Title, Items: playlist.Items}
 	}
 }
@@ -0,0 +1,249 @@
+package youtube
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// YTDLPBackend implements Backend by shelling out to yt-dlp
+type YTDLPBackend struct {
+	// Binary is the yt-dlp executable to run
+	Binary string
+	// ExtraArgs are passed to every yt-dlp invocation
+	ExtraArgs []string
+	// FFmpeg is the ffmpeg executable used to embed cover art
+	FFmpeg string
+}
+
+// NewYTDLPBackend creates a yt-dlp backend using the binaries found in PATH
+func NewYTDLPBackend() *YTDLPBackend {
+	return &YTDLPBackend{Binary: "yt-dlp", FFmpeg: "ffmpeg"}
+}
+
+// command builds a yt-dlp invocation with the configured extra arguments.
+// yt-dlp and everything it spawns (ffmpeg) are killed when ctx is done.
+func (y *YTDLPBackend) command(ctx context.Context, args ...string) *exec.Cmd {
+	all := make([]string, 0, len(y.ExtraArgs)+len(args))
+	all = append(all, y.ExtraArgs...)
+	all = append(all, args...)
+	cmd := exec.CommandContext(ctx, y.Binary, all...)
+	setProcessGroup(cmd)
+	// Don't hang on pipes held open by children that outlive the kill
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+// output runs a yt-dlp command and returns its stdout. A cancelled ctx is
+// reported as ctx.Err() rather than the kill signal, and a failed run as a
+// DownloadError classified from stderr.
+func (y *YTDLPBackend) output(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := y.command(ctx, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := startProcess(cmd); err != nil {
+		return nil, err
+	}
+	err := waitProcess(cmd)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, classifyError(stderr.String(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Search performs a YouTube search with the given query and limit
+func (y *YTDLPBackend) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	output, err := y.output(ctx,
+		fmt.Sprintf("ytsearch%d:%s", limit, query),
+		"--flat-playlist",
+		"--print", "%(title)s|||%(id)s|||%(channel|)s",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseFlatList(output), nil
+}
+
+// Metadata retrieves detailed metadata for a video
+func (y *YTDLPBackend) Metadata(ctx context.Context, videoID string) (*VideoMetadata, error) {
+	output, err := y.output(ctx, "-j", VideoURL(videoID))
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata VideoMetadata
+	if err := json.Unmarshal(output, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// Download downloads a video's audio in the requested format with embedded
+// cover art and metadata, reporting progress parsed from yt-dlp's output
+func (y *YTDLPBackend) Download(ctx context.Context, req DownloadRequest, progress ProgressFunc) (string, error) {
+	args := req.ytdlpAudioArgs()
+	// Without a known title, yt-dlp cleans up the one it fetches itself
+	if req.Title == "" {
+		args = append(args, ytdlpTitleArgs...)
+	}
+	// The thumbnail is made into cover art by finishCover rather than
+	// embedded as is, letterboxing and all
+	var thumbnails string
+	if req.Cover == "" && (req.embedsThumbnail() || req.writesCoverFile()) {
+		if dir, err := os.MkdirTemp("", "music-download-thumbnail-"); err == nil {
+			thumbnails = dir
+			defer os.RemoveAll(dir)
+			args = append(args,
+				"--write-thumbnail",
+				"--convert-thumbnails", "jpg",
+				"-o", "thumbnail:"+ytdlpEscape(filepath.Join(dir, "thumbnail"))+".%(ext)s",
+			)
+		}
+	}
+	args = append(args, req.ytdlpTagArgs()...)
+	args = append(args,
+		"--add-metadata",
+		"--quiet", // Only our progress template lines reach stdout
+		"--no-warnings",
+		"-o", req.ytdlpOutputTemplate(),
+		"--print", "before_dl:"+partialLinePrefix+"%(filename)s",
+		"--print", "after_move:"+filepathLinePrefix+"%(filepath)s",
+	)
+	args = append(args, ytdlpProgressArgs...)
+	args = append(args, VideoURL(req.VideoID))
+	cmd := y.command(ctx, args...)
+
+	// stderr is kept, not shown, so failures can be classified without
+	// breaking the TUI
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := startProcess(cmd); err != nil {
+		return "", err
+	}
+
+	var path string
+	var partial *partialFiles
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := strings.CutPrefix(line, filepathLinePrefix); ok {
+			path = p
+			continue
+		}
+		if p, ok := strings.CutPrefix(line, partialLinePrefix); ok {
+			partial = newPartialFiles(p, req.extension())
+			continue
+		}
+		if p, ok := parseProgressLine(line); ok && progress != nil {
+			progress(p)
+		}
+	}
+
+	if err := waitProcess(cmd); err != nil {
+		if ctx.Err() != nil {
+			partial.remove()
+			return "", ctx.Err()
+		}
+		return "", classifyError(stderr.String(), err)
+	}
+	// The song is saved at this point; cover art or lyrics that can't be
+	// made only warn, even when ctx is cancelled meanwhile
+	var warning error
+	if err := y.finishCover(ctx, req, path, thumbnails); err != nil {
+		warning = Warn(warning, fmt.Errorf("cover art: %w", err))
+	}
+	if req.Lyrics.Enabled {
+		if err := y.fetchLyrics(ctx, req, path); err != nil {
+			warning = Warn(warning, fmt.Errorf("lyrics: %w", err))
+		}
+	}
+	return path, warning
+}
+
+// Playlist retrieves a YouTube playlist and all of its items
+func (y *YTDLPBackend) Playlist(ctx context.Context, playlistID string) (*Playlist, error) {
+	output, err := y.output(ctx,
+		"--flat-playlist",
+		"-J",
+		PlaylistURL(playlistID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Title   string `json:"title"`
+		Entries []struct {
+			Title   string `json:"title"`
+			ID      string `json:"id"`
+			Channel string `json:"channel"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %w", err)
+	}
+
+	playlist := &Playlist{ID: playlistID, Title: info.Title}
+	for _, entry := range info.Entries {
+		playlist.Items = append(playlist.Items, SearchResult{Title: entry.Title, ID: entry.ID, Channel: entry.Channel})
+	}
+	return playlist, nil
+}
+
+// parseFlatList parses "title|||id|||channel" lines printed by --flat-playlist
+func parseFlatList(output []byte) []SearchResult {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	results := make([]SearchResult, 0, len(lines))
+
+	for _, line := range lines {
+		parts := strings.Split(line, "|||")
+		if len(parts) >= 2 {
+			result := SearchResult{
+				Title: parts[0],
+				ID:    parts[1],
+			}
+			if len(parts) > 2 {
+				result.Channel = parts[2]
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// ytdlpTagArgs makes the metadata postprocessor write req's tags instead of
+// the raw video title and channel. The ffmpeg options are appended after
+// yt-dlp's own, so they take precedence.
+func (req DownloadRequest) ytdlpTagArgs() []string {
+	var opts []string
+	for _, tag := range req.WrittenTags().fields() {
+		if tag.value != "" {
+			opts = append(opts, "-metadata", shellQuote(tag.key+"="+tag.value))
+		}
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return []string{"--postprocessor-args", "Metadata+ffmpeg_o:" + strings.Join(opts, " ")}
+}
+
+// shellQuote quotes s for yt-dlp, which splits postprocessor arguments like
+// a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}